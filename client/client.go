@@ -4,8 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/cedws/discord-delete/client/datapackage"
+	"github.com/cedws/discord-delete/client/ledger"
 	"github.com/cedws/discord-delete/client/snowflake"
 	"github.com/cedws/discord-delete/client/spoof"
 
@@ -14,21 +20,50 @@ import (
 
 const day = time.Hour * 24
 
-// https://discord.com/developers/docs/resources/channel#message-object-message-types
+// Milliseconds to wait between deleting messages
+// A delay which is too short will cause the server to return 429 and force us to wait a while
+// By preempting the server's delay, we can reduce the number of requests made to the server
+const minSleep = 200
+
+// https://discord.com/developers/docs/resources/channel#message-object-message-flags
 const (
-	UserMessage = 0
-	UserReply   = 19
+	SuppressEmbedsFlag = 1 << 2
 )
 
 // https://discord.com/developers/docs/resources/channel#channel-object-channel-types
 const (
-	DirectChannel = 1
+	GuildTextChannel         = 0
+	DirectChannel            = 1
+	GroupDirectChannel       = 3
+	GuildCategoryChannel     = 4
+	GuildAnnouncementChannel = 5
+	GuildForumChannel        = 15
+	GuildMediaChannel        = 16
+)
+
+// Ways of finding the user's messages
+const (
+	// SearchMode uses the search index, which covers whole guilds but may be
+	// incomplete
+	SearchMode = "search"
+	// HistoryMode walks the full history of each channel, which is exact but
+	// slow for busy channels
+	HistoryMode = "history"
 )
 
-var ErrorInvalidDuration = errors.New("error parsing duration")
+var (
+	ErrorInvalidDuration = errors.New("error parsing duration")
+	ErrorInvalidMode     = errors.New("mode must be either search or history")
+	ErrorNotFound        = errors.New("resource not found")
+	ErrorInvalidToken    = errors.New("token was rejected")
+	ErrorForbidden       = errors.New("missing access")
+)
 
 type Me struct {
-	ID string `json:"id"`
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	MFAEnabled bool   `json:"mfa_enabled"`
+	Verified   bool   `json:"verified"`
 }
 
 type Channel struct {
@@ -36,6 +71,32 @@ type Channel struct {
 	ID         string      `json:"id"`
 	Recipients []Recipient `json:"recipients"`
 	Name       string      `json:"name,omitempty"`
+	ParentID   string      `json:"parent_id,omitempty"`
+	Position   int         `json:"position,omitempty"`
+}
+
+// DisplayName returns a human readable name for the channel to use in logs.
+func (ch Channel) DisplayName() string {
+	switch ch.Type {
+	case DirectChannel, GroupDirectChannel:
+		if ch.Name != "" {
+			return ch.Name
+		}
+		if len(ch.Recipients) == 0 {
+			return ch.ID
+		}
+
+		names := make([]string, len(ch.Recipients))
+		for i, recipient := range ch.Recipients {
+			names[i] = recipient.Username
+		}
+		return strings.Join(names, ", ")
+	default:
+		if ch.Name != "" {
+			return ch.Name
+		}
+		return ch.ID
+	}
 }
 
 type Recipient struct {
@@ -50,11 +111,45 @@ type Relationship struct {
 }
 
 type Message struct {
-	ID        string `json:"id"`
-	Hit       bool   `json:"hit,omitempty"`
-	ChannelID string `json:"channel_id"`
-	Type      int    `json:"type"`
-	Pinned    bool   `json:"pinned"`
+	ID        string     `json:"id"`
+	Hit       bool       `json:"hit,omitempty"`
+	ChannelID string     `json:"channel_id"`
+	GuildID   string     `json:"guild_id,omitempty"`
+	Author    Recipient  `json:"author"`
+	Content   string     `json:"content"`
+	Type      int        `json:"type"`
+	Flags     int        `json:"flags"`
+	Pinned    bool       `json:"pinned"`
+	Reactions []Reaction `json:"reactions,omitempty"`
+	// handled is set on messages which an earlier search already dealt with
+	handled bool
+}
+
+// MessageEdit is the body of a request to edit a message.
+type MessageEdit struct {
+	Content string `json:"content"`
+	Embeds  []any  `json:"embeds"`
+	Flags   int    `json:"flags"`
+}
+
+type Reaction struct {
+	Count int   `json:"count"`
+	Me    bool  `json:"me"`
+	Emoji Emoji `json:"emoji"`
+}
+
+type Emoji struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name"`
+}
+
+// APIName returns the emoji in the form used in reaction endpoints, which is
+// name:id for custom emoji and the emoji itself otherwise.
+func (e Emoji) APIName() string {
+	if e.ID != "" {
+		return e.Name + ":" + e.ID
+	}
+	return e.Name
 }
 
 type Messages struct {
@@ -63,73 +158,359 @@ type Messages struct {
 	Threads      []Thread    `json:"threads"`
 }
 
+// setGuild records the guild that the messages were found in, because search
+// results don't always include it.
+func (m Messages) setGuild(guildID string) {
+	for _, ctx := range m.Messages {
+		for i := range ctx {
+			if ctx[i].GuildID == "" {
+				ctx[i].GuildID = guildID
+			}
+		}
+	}
+}
+
+// record adds the IDs of the messages that matched the search to ids.
+func (m Messages) record(ids map[string]bool) {
+	for _, ctx := range m.Messages {
+		for _, msg := range ctx {
+			if msg.Hit {
+				ids[msg.ID] = true
+			}
+		}
+	}
+}
+
+// markHandled flags the messages whose IDs are in ids as already dealt with.
+func (m Messages) markHandled(ids map[string]bool) {
+	for _, ctx := range m.Messages {
+		for i := range ctx {
+			if ids[ctx[i].ID] {
+				ctx[i].handled = true
+			}
+		}
+	}
+}
+
+// oldestID returns the lowest ID of the messages that matched the search, or
+// zero if there are none.
+func (m Messages) oldestID() int64 {
+	var oldest int64
+	for _, ctx := range m.Messages {
+		for _, msg := range ctx {
+			if !msg.Hit {
+				continue
+			}
+
+			id, err := strconv.ParseInt(msg.ID, 10, 64)
+			if err != nil {
+				continue
+			}
+			if oldest == 0 || id < oldest {
+				oldest = id
+			}
+		}
+	}
+	return oldest
+}
+
 type Thread struct {
-	ID       string
+	ID       string `json:"id"`
+	ParentID string `json:"parent_id"`
+	OwnerID  string `json:"owner_id"`
+	Name     string `json:"name"`
 	Metadata struct {
-		Archived bool
-		Locked   bool
+		Archived         bool   `json:"archived"`
+		Locked           bool   `json:"locked"`
+		ArchiveTimestamp string `json:"archive_timestamp"`
 	} `json:"thread_metadata"`
 }
 
+type ThreadMember struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+}
+
+type Threads struct {
+	Threads []Thread       `json:"threads"`
+	Members []ThreadMember `json:"members"`
+	HasMore bool           `json:"has_more"`
+}
+
 type ServerWait struct {
 	RetryAfter float32 `json:"retry_after"`
 }
 
 type Client struct {
-	deletedCount int
-	requestCount int
-	token        string
-	spoof        spoof.Info
-	dryRun       bool
-	maxID        int64
-	minID        int64
-	skipChannels []string
-	skipPinned   bool
-	httpClient   http.Client
+	deletedCount      int
+	reactionCount     int
+	editedCount       int
+	lockedMessages    map[string]bool
+	requestCount      int
+	staleCount        int
+	token             string
+	spoof             spoof.Info
+	me                Me
+	log               *log.Entry
+	dryRun            bool
+	maxID             int64
+	minID             int64
+	skip              Rules
+	skipValues        []string
+	only              Rules
+	onlyValues        []string
+	guildChannels     map[string][]Channel
+	guildMessages     map[string]bool
+	knownScopes       map[string]bool
+	mode              string
+	skipPinned        bool
+	deleteTypes       map[int]bool
+	keepLast          int
+	keepWithin        time.Duration
+	keeper            *keepTracker
+	unarchiveThreads  bool
+	unarchived        map[string]bool
+	searchThreads     bool
+	reactions         bool
+	scrubText         string
+	editOnly          bool
+	redactPattern     *regexp.Regexp
+	redactReplacement string
+	redactions        []Redaction
+	leaveGroupDMs     bool
+	dataPackage       []datapackage.Channel
+	openOnly          bool
+	ledger            *ledger.Ledger
+	verify            bool
+	discrepancies     []string
+	confirm           ConfirmFunc
+	confirmedAll      bool
+	onDelete          func(msg Message)
+	stopped           int32
+	httpClient        http.Client
 }
 
 func New(token string) (c Client) {
 	return Client{
-		token:      token,
-		spoof:      spoof.RandomInfo(),
-		httpClient: http.Client{},
+		token:          token,
+		spoof:          spoof.RandomInfo(),
+		log:            log.NewEntry(log.StandardLogger()),
+		mode:           SearchMode,
+		skip:           Rules{ids: make(map[string]bool)},
+		only:           Rules{ids: make(map[string]bool)},
+		lockedMessages: make(map[string]bool),
+		guildChannels:  make(map[string][]Channel),
+		knownScopes:    make(map[string]bool),
+		deleteTypes:    DefaultTypePolicy(),
+		httpClient:     http.Client{},
+	}
+}
+
+// ParseDuration parses a duration which may also be given in days, such as 30d.
+func ParseDuration(value string) (time.Duration, error) {
+	if days := strings.TrimSuffix(value, "d"); days != value {
+		n, err := strconv.ParseUint(days, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrorInvalidDuration, value)
+		}
+		return time.Duration(n) * day, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrorInvalidDuration, value)
 	}
+	return d, nil
+}
+
+// SetLogger logs through entry, so that its fields tell apart clients running
+// at the same time.
+func (c *Client) SetLogger(entry *log.Entry) {
+	c.log = entry
 }
 
 func (c *Client) SetDryRun(dryRun bool) {
 	c.dryRun = dryRun
 }
 
-func (c *Client) SetSkipChannels(skipChannels []string) {
-	c.skipChannels = skipChannels
+func (c *Client) SetSkipChannels(skipChannels []string) (err error) {
+	c.skip, err = ParseRules(skipChannels)
+	c.skipValues = skipChannels
+	return
+}
+
+func (c *Client) SetOnlyChannels(onlyChannels []string) (err error) {
+	c.only, err = ParseRules(onlyChannels)
+	c.onlyValues = onlyChannels
+	return
+}
+
+func (c *Client) SetMode(mode string) error {
+	if mode != SearchMode && mode != HistoryMode {
+		return ErrorInvalidMode
+	}
+	c.mode = mode
+	return nil
+}
+
+// SetTypes changes which message types are deleted, see ParseTypePolicy.
+func (c *Client) SetTypes(types []string) (err error) {
+	policy, err := ParseTypePolicy(types)
+	if err != nil {
+		return err
+	}
+	c.deleteTypes = policy
+	return nil
 }
 
 func (c *Client) SetSkipPinned(skipPinned bool) {
 	c.skipPinned = skipPinned
 }
 
+func (c *Client) SetUnarchiveThreads(unarchiveThreads bool) {
+	c.unarchiveThreads = unarchiveThreads
+}
+
+func (c *Client) SetSearchThreads(searchThreads bool) {
+	c.searchThreads = searchThreads
+}
+
+func (c *Client) SetLeaveGroupDMs(leaveGroupDMs bool) {
+	c.leaveGroupDMs = leaveGroupDMs
+}
+
+func (c *Client) SetDataPackage(channels []datapackage.Channel) {
+	c.dataPackage = channels
+}
+
+// SetOpenOnly only deletes from DMs which are already open, rather than
+// opening DMs with friends and users from the data package.
+func (c *Client) SetOpenOnly(openOnly bool) {
+	c.openOnly = openOnly
+}
+
+func (c *Client) SetLedger(ledger *ledger.Ledger) {
+	c.ledger = ledger
+}
+
+func (c *Client) SetVerify(verify bool) {
+	c.verify = verify
+}
+
+// SetKeepLast keeps the given number of most recent messages in each channel.
+func (c *Client) SetKeepLast(keepLast uint) {
+	c.keepLast = int(keepLast)
+}
+
+// SetKeepNewerThan keeps messages sent within the given duration of the most
+// recent message in each channel.
+func (c *Client) SetKeepNewerThan(keepWithin time.Duration) {
+	c.keepWithin = keepWithin
+}
+
+// SetReactions removes the user's reactions from messages in every channel
+// whose history is walked.
+func (c *Client) SetReactions(reactions bool) {
+	c.reactions = reactions
+}
+
+// SetScrub overwrites the content of messages with text before deleting them,
+// so that anyone who cached the message sees the scrubbed version last.
+func (c *Client) SetScrub(text string) {
+	c.scrubText = text
+}
+
+// SetEditOnly scrubs messages without deleting them.
+func (c *Client) SetEditOnly(editOnly bool) {
+	c.editOnly = editOnly
+}
+
+// SetOnDelete calls onDelete after each message is deleted, for reporting
+// progress.
+func (c *Client) SetOnDelete(onDelete func(msg Message)) {
+	c.onDelete = onDelete
+}
+
+// Stop makes a run in progress return ErrorQuit before the next message, so
+// that threads are archived again on the way out. It's safe to call from
+// another goroutine, such as a signal handler.
+func (c *Client) Stop() {
+	atomic.StoreInt32(&c.stopped, 1)
+}
+
+func (c *Client) stopping() bool {
+	return atomic.LoadInt32(&c.stopped) != 0
+}
+
+// DeletedCount returns the number of messages deleted so far.
+func (c *Client) DeletedCount() int {
+	return c.deletedCount
+}
+
+// Reset prepares a client which is used for more than one run for the next.
+// Counts start over so that each run is reported on its own, and channels are
+// listed and matched against the rules again so that new ones are found.
+func (c *Client) Reset() {
+	c.deletedCount = 0
+	c.reactionCount = 0
+	c.editedCount = 0
+	c.requestCount = 0
+	c.staleCount = 0
+	c.lockedMessages = make(map[string]bool)
+	c.discrepancies = nil
+	c.redactions = nil
+
+	c.guildChannels = make(map[string][]Channel)
+	c.knownScopes = make(map[string]bool)
+	// The rules were valid when they were set, so they parse again
+	c.skip, _ = ParseRules(c.skipValues)
+	c.only, _ = ParseRules(c.onlyValues)
+}
+
 func (c *Client) SetMinAge(minAge uint) error {
+	if minAge == 0 {
+		c.maxID = 0
+		return nil
+	}
+
 	t := time.Now().Add(-time.Duration(minAge) * day)
 	millis := t.UnixNano() / int64(time.Millisecond)
 
 	c.maxID = snowflake.ToSnowflake(millis)
-	log.Debugf("message maximum ID must be %v", c.maxID)
+	c.log.Debugf("message maximum ID must be %v", c.maxID)
 
 	return nil
 }
 
 func (c *Client) SetMaxAge(maxAge uint) error {
+	if maxAge == 0 {
+		c.minID = 0
+		return nil
+	}
+
 	t := time.Now().Add(-time.Duration(maxAge) * day)
 	millis := t.UnixNano() / int64(time.Millisecond)
 
 	c.minID = snowflake.ToSnowflake(millis)
-	log.Debugf("message minimum ID must be %v", c.minID)
+	c.log.Debugf("message minimum ID must be %v", c.minID)
 
 	return nil
 }
 
+// SetMaxID only includes messages with IDs below the snowflake, 0 clears the
+// limit. It is the same limit as SetMinAge sets.
+func (c *Client) SetMaxID(id int64) {
+	c.maxID = id
+}
+
+// SetMinID only includes messages with IDs above the snowflake, 0 clears the
+// limit. It is the same limit as SetMaxAge sets.
+func (c *Client) SetMinID(id int64) {
+	c.minID = id
+}
+
 func (c *Client) Delete() error {
-	me, err := c.Me()
+	me, err := c.profile()
 	if err != nil {
 		return fmt.Errorf("error fetching profile information: %w", err)
 	}
@@ -139,10 +520,32 @@ func (c *Client) Delete() error {
 		return fmt.Errorf("error fetching channels: %w", err)
 	}
 
+	// Recipients we already have a DM open with, so that we don't open it again
+	dms := make(map[string]bool)
+
 	for _, channel := range channels {
+		c.knownScopes[channel.ID] = true
+		if channel.Type == DirectChannel && len(channel.Recipients) == 1 {
+			dms[channel.Recipients[0].ID] = true
+		}
+
 		if err = c.DeleteFromChannel(me, channel); err != nil {
 			return err
 		}
+
+		if c.leaveGroupDMs && channel.Type == GroupDirectChannel && c.wantChannel(channel.ID) {
+			if err = c.leaveGroupDM(channel); err != nil {
+				return err
+			}
+		}
+	}
+
+	guilds, err := c.Guilds()
+	if err != nil {
+		return fmt.Errorf("error fetching guilds: %w", err)
+	}
+	for _, guild := range guilds {
+		c.knownScopes[guild.ID] = true
 	}
 
 	relationships, err := c.Relationships()
@@ -150,63 +553,178 @@ func (c *Client) Delete() error {
 		return fmt.Errorf("error fetching relationships: %w", err)
 	}
 
-Relationships:
 	for _, relation := range relationships {
-		for _, channel := range channels {
-			// If the relation is the sole recipient in one of the channels we found
-			// earlier, skip it.
-			if channel.Type == DirectChannel && channel.Recipients[0].ID == relation.ID {
-				log.Debugf("skipping resolving relation %v because the user already has the channel open", relation.ID)
-				continue Relationships
-			}
+		// If the relation is the sole recipient in one of the channels we found
+		// earlier, skip it.
+		if dms[relation.ID] {
+			c.log.Debugf("skipping resolving relation %v because the user already has the channel open", relation.ID)
+			continue
+		}
+
+		// Avoid opening DMs which are going to be skipped anyway
+		dm := Channel{Recipients: []Recipient{relation.Recipient}}
+		if c.skip.MatchDM(dm) {
+			c.log.Infof("skipping message deletion for relation '%v'", relation.Recipient.Username)
+			continue
+		}
+		if !c.mayIncludeDM(dm) {
+			c.log.Debugf("relation '%v' not selected, skipping", relation.Recipient.Username)
+			continue
+		}
+
+		if c.openOnly {
+			c.log.Infof("skipping relation '%v' because no DM is open with them", relation.Recipient.Username)
+			continue
 		}
 
 		channel, err := c.RelationshipChannel(relation.Recipient)
+		if errors.Is(err, ErrorForbidden) {
+			c.log.Warnf("not allowed to open DM with '%v', skipping", relation.Recipient.Username)
+			continue
+		}
 		if err != nil {
 			return fmt.Errorf("error resolving relationship to channel: %w", err)
 		}
+		dms[relation.ID] = true
+		c.knownScopes[channel.ID] = true
 
-		log.Infof("resolved relationship with '%v' to channel %v", relation.Recipient.Username, channel.ID)
+		c.log.Infof("resolved relationship with '%v' to channel %v", relation.Recipient.Username, channel.ID)
 
 		if err = c.DeleteFromChannel(me, channel); err != nil {
 			return err
 		}
 	}
 
-	guilds, err := c.Guilds()
-	if err != nil {
-		return fmt.Errorf("error fetching guilds: %w", err)
-	}
 	for _, guild := range guilds {
 		if err = c.DeleteFromGuild(me, guild); err != nil {
 			return err
 		}
 	}
 
-	log.Infof("finished deleting messages: %v deleted in %v total requests", c.deletedCount, c.requestCount)
+	if len(c.dataPackage) > 0 {
+		if err = c.DeleteFromDataPackage(me, channels, dms, guilds); err != nil {
+			return err
+		}
+	}
+
+	if len(c.lockedMessages) > 0 {
+		c.log.Warnf("%v messages could not be deleted because they are in locked threads", len(c.lockedMessages))
+	}
+	if c.verify {
+		c.reportDiscrepancies()
+	}
+	if c.reactions {
+		c.log.Infof("removed %v reactions", c.reactionCount)
+	}
+	if c.scrubText != "" {
+		c.log.Infof("scrubbed %v messages", c.editedCount)
+	}
+	if c.redactPattern != nil {
+		c.log.Infof("finished redacting messages: %v redacted in %v total requests", len(c.redactions), c.requestCount)
+		return nil
+	}
+	c.log.Infof("finished deleting messages: %v deleted in %v total requests", c.deletedCount, c.requestCount)
 
 	return nil
 }
 
 func (c *Client) DeleteFromChannel(me Me, channel Channel) error {
+	c.resolveDM(channel)
+
 	if c.skipChannel(channel.ID) {
-		log.Infof("skipping message deletion for channel %v", channel.ID)
+		c.log.Infof("skipping message deletion for channel '%v'", channel.DisplayName())
+		return nil
+	}
+	if !c.includeChannel(channel.ID) {
+		c.log.Debugf("channel '%v' not selected, skipping", channel.DisplayName())
 		return nil
 	}
 
-	offset := 0
+	if c.walkHistory() {
+		return c.DeleteFromHistory(me, channel)
+	}
 
-	for {
-		results, err := c.ChannelMessages(channel, me, offset)
-		if err != nil {
-			return fmt.Errorf("error fetching messages for channel: %w", err)
+	search := func(before int64) (Messages, error) {
+		return c.ChannelMessages(channel, me, before)
+	}
+
+	return c.deleteFromScope("channel", channel.ID, channel.DisplayName(), search)
+}
+
+func (c *Client) DeleteFromGuild(me Me, channel Channel) error {
+	if err := c.resolveGuild(channel); err != nil {
+		return fmt.Errorf("error resolving channels for guild: %w", err)
+	}
+
+	if c.skipChannel(channel.ID) {
+		c.log.Infof("skipping message deletion for guild '%v'", channel.Name)
+		return nil
+	}
+	if !c.includeChannel(channel.ID) {
+		if !c.only.mayMatchInGuild(channel, c.knownScopes) {
+			c.log.Debugf("guild '%v' not selected, skipping", channel.Name)
+			return nil
 		}
-		if len(results.Messages) == 0 {
-			log.Infof("no more messages to delete for channel %v", channel.ID)
-			break
+		return c.DeleteFromGuildChannels(me, channel)
+	}
+
+	if c.reactions {
+		c.log.Warnf("reactions can't be found by searching guild '%v', select its channels with --only to remove them", channel.Name)
+	}
+
+	// Thread messages found by the guild search are left alone by the thread
+	// pass, so that none of them are handled twice
+	found := make(map[string]bool)
+
+	search := func(before int64) (Messages, error) {
+		results, err := c.GuildMessages(channel, me, before)
+		results.setGuild(channel.ID)
+		results.record(found)
+		return results, err
+	}
+
+	if err := c.deleteFromScope("guild", channel.ID, channel.Name, search); err != nil {
+		return err
+	}
+
+	// The user may have chosen to skip the guild when asked
+	if c.searchThreads && !c.skipChannel(channel.ID) {
+		c.guildMessages = found
+		defer func() { c.guildMessages = nil }()
+
+		return c.DeleteFromThreads(me, channel)
+	}
+
+	return nil
+}
+
+// DeleteFromGuildChannels deletes from the channels in the guild which were
+// selected individually rather than searching the whole guild.
+func (c *Client) DeleteFromGuildChannels(me Me, guild Channel) error {
+	channels, err := c.guildChannelList(guild)
+	if err != nil {
+		return fmt.Errorf("error fetching channels for guild: %w", err)
+	}
+
+	for _, channel := range channels {
+		if channel.Type == GuildCategoryChannel || !c.wantChannel(channel.ID) {
+			continue
+		}
+
+		if c.walkHistory() {
+			if err := c.deleteFromHistory(me, channel, guild.ID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		search := func(before int64) (Messages, error) {
+			results, err := c.GuildChannelMessages(guild, channel, me, before)
+			results.setGuild(guild.ID)
+			return results, err
 		}
 
-		if err = c.DeleteMessages(results, &offset); err != nil {
+		if err := c.deleteFromScope("channel", channel.ID, channel.Name, search); err != nil {
 			return err
 		}
 	}
@@ -214,106 +732,391 @@ func (c *Client) DeleteFromChannel(me Me, channel Channel) error {
 	return nil
 }
 
-func (c *Client) DeleteFromGuild(me Me, channel Channel) error {
-	if c.skipChannel(channel.ID) {
-		log.Infof("skipping message deletion for guild '%v'", channel.Name)
+// searchFunc fetches a page of the user's messages within a channel, guild or
+// thread, older than the message ID before, or the newest messages if zero.
+type searchFunc func(before int64) (Messages, error)
+
+// deleteFromScope deletes every message found by search, then verifies that
+// nothing remains if the user asked us to.
+func (c *Client) deleteFromScope(kind string, id string, name string, search searchFunc) error {
+	ok, err := c.confirmScope(kind, id, name, search)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		c.log.Infof("skipping message deletion for %v '%v'", kind, name)
 		return nil
 	}
 
-	offset := 0
+	skipped, err := c.deletePass(kind, name, search)
+	if err != nil {
+		return err
+	}
+
+	if c.verify {
+		return c.verifyScope(kind, name, search, skipped)
+	}
+
+	return nil
+}
+
+// deletePass runs through the search results until there are none left. It
+// returns the number of messages that were left in place.
+func (c *Client) deletePass(kind string, name string, search searchFunc) (int, error) {
+	var before int64
+	kept := 0
+
+	// Messages we kept last pass are still there, so start counting again
+	c.keeper = newKeepTracker(c.keepLast, c.keepWithin)
+
+	// A thread may have messages on several pages, so it is only archived again
+	// once the whole scope is done
+	c.unarchived = make(map[string]bool)
+	defer func() {
+		c.rearchiveThreads(c.unarchived)
+		c.unarchived = nil
+	}()
 
 	for {
-		results, err := c.GuildMessages(channel, me, offset)
+		if c.stopping() {
+			return 0, ErrorQuit
+		}
+
+		results, err := search(before)
+		if errors.Is(err, ErrorForbidden) {
+			c.log.Warnf("no access to %v '%v', skipping", kind, name)
+			return kept, nil
+		}
 		if err != nil {
-			return fmt.Errorf("error fetching messages for guild: %w", err)
+			return 0, fmt.Errorf("error fetching messages for %v: %w", kind, err)
 		}
 		if len(results.Messages) == 0 {
-			log.Infof("no more messages to delete for guild '%v'", channel.Name)
+			c.log.Infof("no more messages to delete for %v '%v'", kind, name)
 			break
 		}
 
-		if err = c.DeleteMessages(results, &offset); err != nil {
-			return err
+		n, err := c.DeleteMessages(results)
+		if err != nil {
+			return 0, err
 		}
+		kept += n
+
+		// Continue from the oldest message on this page, whether or not it was
+		// deleted, so we never see the same message twice
+		oldest := results.oldestID()
+		if oldest == 0 || (before != 0 && oldest >= before) {
+			c.log.Infof("no more messages to delete for %v '%v'", kind, name)
+			break
+		}
+		before = oldest
 	}
 
-	return nil
+	return kept, nil
 }
 
-func (c *Client) DeleteMessages(messages Messages, offset *int) error {
-	// Milliseconds to wait between deleting messages
-	// A delay which is too short will cause the server to return 429 and force us to wait a while
-	// By preempting the server's delay, we can reduce the number of requests made to the server
-	const minSleep = 200
-
-	archived := make(map[string]bool)
+// DeleteMessages deletes the user's messages from a page of search results. It
+// returns the number of messages that were left in place.
+func (c *Client) DeleteMessages(messages Messages) (kept int, err error) {
+	threads := make(map[string]Thread)
 	for _, thread := range messages.Threads {
-		archived[thread.ID] = thread.Metadata.Archived || thread.Metadata.Locked
+		threads[thread.ID] = thread
+	}
+
+	// Threads we unarchived are archived again once we're done so that the
+	// thread looks untouched, after the whole pass if there is one
+	unarchived := c.unarchived
+	if unarchived == nil {
+		unarchived = make(map[string]bool)
+		defer c.rearchiveThreads(unarchived)
 	}
 
 	for _, ctx := range messages.Messages {
 		for _, msg := range ctx {
+			if c.stopping() {
+				return kept, ErrorQuit
+			}
+
 			if !msg.Hit {
 				// message is for context but may not be authored by this user
-				log.Debugf("skipping context message")
+				c.log.Debugf("skipping context message")
 				continue
 			}
 
-			if archived[msg.ChannelID] {
-				// TODO: try to unarchive the thread
-				log.Debugf("message is in archived or locked thread %v", msg.ChannelID)
-				(*offset)++
+			if msg.handled {
+				// Whatever happened to it, it isn't counted or deleted twice
+				c.log.Debugf("message %v was already found by the guild search", msg.ID)
+				kept++
 				continue
 			}
 
-			if msg.Type != UserMessage && msg.Type != UserReply {
+			// Check if this message is in our list of channels to skip
+			// This will only skip this specific message and count it as kept
+			// Entire channels should be skipped at the caller of this function
+			// We do it this way because guilds searches return a mix of messages
+			// from any channel, including threads whose parent is skipped
+			thread, inThread := threads[msg.ChannelID]
+			if c.skipChannel(msg.ChannelID) || inThread && c.skipChannel(thread.ParentID) {
+				c.log.Infof("skipping message deletion for channel %v", msg.ChannelID)
+				kept++
+				continue
+			}
+
+			if inThread && !c.threadWritable(thread, unarchived) {
+				if thread.Metadata.Locked {
+					// Keyed by ID because the same message may be found more than once
+					c.lockedMessages[msg.ID] = true
+				}
+				kept++
+				continue
+			}
+
+			if !c.deleteTypes[msg.Type] {
 				// message is not text but could be an action for example
-				log.Debugf("found message of type %v, seeking ahead", msg.Type)
-				(*offset)++
+				c.log.Debugf("found message of type %v, skipping", msg.Type)
+				kept++
 				continue
 			}
 
 			if c.skipPinned && msg.Pinned {
-				log.Infof("found pinned message, skipping")
-				(*offset)++
+				c.log.Infof("found pinned message, skipping")
+				kept++
 				continue
 			}
 
-			// Check if this message is in our list of channels to skip
-			// This will only skip this specific message and increment the seek index
-			// Entire channels should be skipped at the caller of this function
-			// We do it this way because guilds searches return a mix of messages
-			// from any channel
-			if c.skipChannel(msg.ChannelID) {
-				log.Infof("skipping message deletion for channel %v", msg.ChannelID)
-				(*offset)++
+			if c.redactPattern != nil {
+				// Redaction never deletes, the message stays in place
+				if err := c.redactMessage(msg); err != nil {
+					return kept, err
+				}
+				kept++
 				continue
 			}
 
-			log.Infof("deleting message %v from channel %v", msg.ID, msg.ChannelID)
-			if c.dryRun {
-				// Move seek index forward to simulate message deletion on server's side
-				(*offset)++
-			} else {
-				if err := c.DeleteMessage(msg); err != nil {
-					return fmt.Errorf("error deleting message: %w", err)
+			if c.keeper != nil && c.keeper.keep(msg) {
+				c.log.Debugf("keeping recent message %v in channel %v", msg.ID, msg.ChannelID)
+				kept++
+				continue
+			}
+
+			if c.scrubText != "" && editable(msg) {
+				if err := c.scrubMessage(msg); err != nil {
+					return kept, err
 				}
-				time.Sleep(minSleep * time.Millisecond)
 			}
+			if c.editOnly {
+				// The message stays in place, only its content has changed
+				kept++
+				continue
+			}
+
+			err := c.deleteMessage(msg)
+			if errors.Is(err, ErrorNotFound) {
+				// Search results can lag behind, so this was deleted already
+				c.log.Infof("message %v in channel %v was already deleted", msg.ID, msg.ChannelID)
+				c.staleCount++
+				continue
+			}
+			if errors.Is(err, ErrorForbidden) {
+				c.log.Warnf("not allowed to delete message %v in channel %v, skipping", msg.ID, msg.ChannelID)
+				kept++
+				continue
+			}
+			if threadArchived(err) {
+				// History pages don't say which messages are in threads, so this
+				// is only found out when deleting
+				c.log.Warnf("message %v is in archived or locked thread %v, skipping", msg.ID, msg.ChannelID)
+				kept++
+				continue
+			}
+			if err != nil {
+				return kept, err
+			}
+			if c.dryRun {
+				// The message is still there as far as the server is concerned
+				kept++
+			}
+		}
+	}
 
-			// Increment regardless of whether it's a dry run
-			c.deletedCount++
+	return kept, nil
+}
+
+// deleteMessage deletes a single message, recording it in the ledger. On a dry
+// run the message is only logged.
+func (c *Client) deleteMessage(msg Message) error {
+	c.log.Infof("deleting message %v from channel %v", msg.ID, msg.ChannelID)
+	if !c.dryRun {
+		if err := c.DeleteMessage(msg); err != nil {
+			return fmt.Errorf("error deleting message: %w", err)
 		}
+		if err := c.recordDeletion(msg); err != nil {
+			return err
+		}
+		time.Sleep(minSleep * time.Millisecond)
+	}
+
+	// Increment regardless of whether it's a dry run
+	c.deletedCount++
+	if c.onDelete != nil {
+		c.onDelete(msg)
 	}
 
 	return nil
 }
 
-func (c *Client) skipChannel(channel string) bool {
-	for _, skip := range c.skipChannels {
-		if channel == skip {
-			return true
+// editable reports whether the message is of a type which can be edited.
+func editable(msg Message) bool {
+	return msg.Type == UserMessage || msg.Type == UserReply
+}
+
+func (c *Client) scrubMessage(msg Message) error {
+	if msg.Content == c.scrubText {
+		c.log.Debugf("message %v has already been scrubbed", msg.ID)
+		return nil
+	}
+
+	c.log.Infof("scrubbing message %v from channel %v", msg.ID, msg.ChannelID)
+	if !c.dryRun {
+		edit := MessageEdit{
+			Content: c.scrubText,
+			Embeds:  []any{},
+			Flags:   msg.Flags | SuppressEmbedsFlag,
+		}
+		_, err := c.EditMessage(msg, edit)
+		if errors.Is(err, ErrorForbidden) {
+			c.log.Warnf("not allowed to scrub message %v in channel %v, skipping", msg.ID, msg.ChannelID)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error scrubbing message: %w", err)
+		}
+		time.Sleep(minSleep * time.Millisecond)
+	}
+
+	c.editedCount++
+
+	return nil
+}
+
+// threadWritable reports whether messages in the thread can be deleted,
+// unarchiving the thread first if the user asked us to.
+func (c *Client) threadWritable(thread Thread, unarchived map[string]bool) bool {
+	if thread.Metadata.Locked {
+		// Only moderators can unarchive a locked thread
+		c.log.Warnf("message is in locked thread %v, skipping", thread.ID)
+		return false
+	}
+	if !thread.Metadata.Archived {
+		return true
+	}
+	if !c.unarchiveThreads {
+		c.log.Debugf("message is in archived thread %v", thread.ID)
+		return false
+	}
+
+	if ok, seen := unarchived[thread.ID]; seen {
+		return ok
+	}
+
+	c.log.Infof("unarchiving thread %v", thread.ID)
+	if c.dryRun {
+		unarchived[thread.ID] = true
+		return true
+	}
+
+	updated, err := c.SetThreadArchived(thread, false)
+	// A forbidden response leaves the thread untouched, so check what the server
+	// sent back rather than relying on the error alone
+	if err != nil || updated.ID == "" || updated.Metadata.Archived {
+		c.log.Warnf("unable to unarchive thread %v, skipping", thread.ID)
+		unarchived[thread.ID] = false
+		return false
+	}
+
+	unarchived[thread.ID] = true
+	return true
+}
+
+func (c *Client) rearchiveThreads(unarchived map[string]bool) {
+	for id, ok := range unarchived {
+		if !ok {
+			continue
+		}
+
+		c.log.Infof("archiving thread %v", id)
+		if c.dryRun {
+			continue
+		}
+
+		if _, err := c.SetThreadArchived(Thread{ID: id}, true); err != nil {
+			c.log.Errorf("error archiving thread %v: %v", id, err)
 		}
 	}
-	return false
+}
+
+func (c *Client) leaveGroupDM(channel Channel) error {
+	c.log.Infof("leaving group DM '%v'", channel.DisplayName())
+	if c.dryRun {
+		return nil
+	}
+
+	err := c.LeaveChannel(channel)
+	if errors.Is(err, ErrorForbidden) {
+		c.log.Warnf("not allowed to leave group DM '%v'", channel.DisplayName())
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error leaving group DM: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) recordDeletion(msg Message) error {
+	if c.ledger == nil {
+		return nil
+	}
+
+	entry := ledger.Entry{
+		UserID:    c.me.ID,
+		MessageID: msg.ID,
+		ChannelID: msg.ChannelID,
+		GuildID:   msg.GuildID,
+		DeletedAt: time.Now().UTC(),
+	}
+	if id, err := strconv.ParseInt(msg.ID, 10, 64); err == nil {
+		entry.Timestamp = time.UnixMilli(snowflake.FromSnowflake(id)).UTC()
+	}
+
+	if err := c.ledger.Record(entry); err != nil {
+		return fmt.Errorf("error recording deletion: %w", err)
+	}
+
+	return nil
+}
+
+// walkHistory reports whether channels should be walked rather than searched,
+// which is the only way to find reactions.
+func (c *Client) walkHistory() bool {
+	return c.mode == HistoryMode || c.reactions
+}
+
+// includeChannel reports whether the channel or guild was selected, which is
+// always the case if no selection was made.
+func (c *Client) includeChannel(channel string) bool {
+	return c.only.Empty() || c.only.Has(channel)
+}
+
+func (c *Client) wantChannel(channel string) bool {
+	return c.includeChannel(channel) && !c.skipChannel(channel)
+}
+
+// mayIncludeDM reports whether a DM which isn't open yet could be selected, so
+// that DMs are only opened when they might be.
+func (c *Client) mayIncludeDM(dm Channel) bool {
+	return c.only.Empty() || c.only.mayMatchDM(dm, c.knownScopes)
+}
+
+func (c *Client) skipChannel(channel string) bool {
+	return c.skip.Has(channel)
 }
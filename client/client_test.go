@@ -0,0 +1,569 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cedws/discord-delete/client/datapackage"
+	"github.com/cedws/discord-delete/client/ledger"
+	"github.com/cedws/discord-delete/client/snowflake"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupDirectChannelDisplayName(t *testing.T) {
+	channel := Channel{
+		Type: GroupDirectChannel,
+		ID:   "12345",
+		Recipients: []Recipient{
+			{Username: "alice"},
+			{Username: "bob"},
+		},
+	}
+	assert.Equal(t, "alice, bob", channel.DisplayName())
+
+	channel.Name = "friends"
+	assert.Equal(t, "friends", channel.DisplayName())
+
+	channel = Channel{Type: GroupDirectChannel, ID: "12345"}
+	assert.Equal(t, "12345", channel.DisplayName())
+}
+
+func TestMessagesOldestID(t *testing.T) {
+	messages := Messages{
+		Messages: [][]Message{
+			{{ID: "300", Hit: true}, {ID: "100"}},
+			{{ID: "200", Hit: true}},
+		},
+	}
+	assert.Equal(t, int64(200), messages.oldestID())
+	assert.Equal(t, int64(0), Messages{}.oldestID())
+}
+
+func TestResolveChannelRefs(t *testing.T) {
+	guild := Channel{ID: "1", Name: "Work"}
+	channels := []Channel{
+		{ID: "10", Name: "general", Type: GuildTextChannel},
+		{ID: "20", Name: "Projects", Type: GuildCategoryChannel},
+		{ID: "21", Name: "alpha", Type: GuildTextChannel, ParentID: "20"},
+		{ID: "22", Name: "beta", Type: GuildTextChannel, ParentID: "20"},
+	}
+
+	assert.Equal(t, []string{"10"}, resolveChannelRefs([]string{"work/#general"}, guild, channels))
+	assert.Equal(t, []string{"20", "21", "22"}, resolveChannelRefs([]string{"Work/Projects"}, guild, channels))
+	assert.Empty(t, resolveChannelRefs([]string{"Other/#general", "12345"}, guild, channels))
+}
+
+func TestRules(t *testing.T) {
+	rules, err := ParseRules([]string{"12345", "guild:Work*", "dm:/^bob\\d+$/", "Work/#general"})
+	assert.Nil(t, err)
+
+	assert.True(t, rules.Has("12345"))
+	assert.True(t, rules.MatchGuild(Channel{Name: "work stuff"}))
+	assert.False(t, rules.MatchGuild(Channel{Name: "Gaming"}))
+	assert.True(t, rules.MatchDM(Channel{Recipients: []Recipient{{Username: "alice"}, {Username: "bob42"}}}))
+	assert.False(t, rules.MatchDM(Channel{Recipients: []Recipient{{Username: "bobby"}}}))
+	assert.Equal(t, []string{"Work/#general"}, rules.refs)
+
+	assert.True(t, rules.mayMatchInGuild(Channel{Name: "Work"}, nil))
+	assert.True(t, rules.mayMatchInGuild(Channel{Name: "Gaming"}, nil))
+	assert.False(t, rules.mayMatchInGuild(Channel{Name: "Gaming"}, map[string]bool{"12345": true}))
+
+	known := map[string]bool{"12345": true}
+	assert.True(t, rules.mayMatchDM(Channel{Recipients: []Recipient{{Username: "bob7"}}}, known))
+	assert.False(t, rules.mayMatchDM(Channel{Recipients: []Recipient{{Username: "alice"}}}, known))
+	assert.True(t, rules.mayMatchDM(Channel{ID: "99"}, known))
+	assert.True(t, rules.mayMatchDM(Channel{Recipients: []Recipient{{Username: "alice"}}}, nil))
+
+	guildOnly, err := ParseRules([]string{"guild:Work"})
+	assert.Nil(t, err)
+	assert.False(t, guildOnly.mayMatchDM(Channel{ID: "99"}, nil))
+
+	_, err = ParseRules([]string{"dm:/(/"})
+	assert.NotNil(t, err)
+}
+
+func TestKeepTracker(t *testing.T) {
+	keeper := newKeepTracker(2, 0)
+	assert.True(t, keeper.keep(Message{ID: "3", ChannelID: "a"}))
+	assert.True(t, keeper.keep(Message{ID: "2", ChannelID: "b"}))
+	assert.True(t, keeper.keep(Message{ID: "2", ChannelID: "a"}))
+	assert.False(t, keeper.keep(Message{ID: "1", ChannelID: "a"}))
+	assert.True(t, keeper.keep(Message{ID: "1", ChannelID: "b"}))
+}
+
+func TestKeepTrackerCombined(t *testing.T) {
+	id := func(millis int64) string {
+		return strconv.FormatInt(snowflake.ToSnowflake(millis), 10)
+	}
+	now := int64(1619910000000)
+
+	keeper := newKeepTracker(1, time.Hour)
+	assert.True(t, keeper.keep(Message{ID: id(now), ChannelID: "a"}))
+	assert.True(t, keeper.keep(Message{ID: id(now - 30*60*1000), ChannelID: "a"}))
+	assert.False(t, keeper.keep(Message{ID: id(now - 2*60*60*1000), ChannelID: "a"}))
+}
+
+func TestParseDuration(t *testing.T) {
+	d, err := ParseDuration("30d")
+	assert.Nil(t, err)
+	assert.Equal(t, 30*day, d)
+
+	d, err = ParseDuration("12h")
+	assert.Nil(t, err)
+	assert.Equal(t, 12*time.Hour, d)
+
+	_, err = ParseDuration("soon")
+	assert.ErrorIs(t, err, ErrorInvalidDuration)
+}
+
+func TestEmojiAPIName(t *testing.T) {
+	assert.Equal(t, "👍", Emoji{Name: "👍"}.APIName())
+	assert.Equal(t, "party:12345", Emoji{ID: "12345", Name: "party"}.APIName())
+}
+
+func TestRedactMessage(t *testing.T) {
+	c := New("")
+	c.SetDryRun(true)
+	c.SetRedact(regexp.MustCompile(`\S+@example\.com`), "[email]")
+
+	assert.Nil(t, c.redactMessage(Message{ID: "1", Content: "mail me at bob@example.com"}))
+	assert.Nil(t, c.redactMessage(Message{ID: "2", Content: "nothing to see here"}))
+	assert.Nil(t, c.redactMessage(Message{ID: "3", Type: 7, Content: "bob@example.com"}))
+
+	redactions := c.Redactions()
+	assert.Len(t, redactions, 1)
+	assert.Equal(t, "1", redactions[0].MessageID)
+	assert.Equal(t, hashContent("mail me at [email]"), redactions[0].AfterHash)
+}
+
+func TestDefaultTypePolicy(t *testing.T) {
+	policy := DefaultTypePolicy()
+
+	deletable := []int{
+		UserMessage,
+		ChannelNameChangeMessage,
+		ChannelPinnedMessage,
+		UserReply,
+		ChatInputCommandMessage,
+		ThreadStarterMessage,
+		ContextMenuCommandMessage,
+		StageStartMessage,
+		StageEndMessage,
+		StageSpeakerMessage,
+		StageTopicMessage,
+	}
+	for _, typ := range deletable {
+		assert.True(t, policy[typ], "type %v", typ)
+	}
+
+	var count int
+	for _, deleted := range policy {
+		if deleted {
+			count++
+		}
+	}
+	assert.Equal(t, len(deletable), count)
+}
+
+func TestParseTypePolicy(t *testing.T) {
+	policy, err := ParseTypePolicy([]string{"-channel_pinned_message", "+7", "-21"})
+	assert.Nil(t, err)
+	assert.True(t, policy[UserMessage])
+	assert.True(t, policy[UserJoinMessage])
+	assert.False(t, policy[ThreadStarterMessage])
+	assert.False(t, policy[ChannelPinnedMessage])
+	assert.False(t, policy[CallMessage])
+
+	_, err = ParseTypePolicy([]string{"bogus"})
+	assert.NotNil(t, err)
+}
+
+func TestParseMessageRef(t *testing.T) {
+	msg, err := ParseMessageRef("10/20")
+	assert.Nil(t, err)
+	assert.Equal(t, Message{ID: "20", ChannelID: "10"}, msg)
+
+	msg, err = ParseMessageRef("https://discord.com/channels/30/10/20")
+	assert.Nil(t, err)
+	assert.Equal(t, Message{ID: "20", ChannelID: "10", GuildID: "30"}, msg)
+
+	msg, err = ParseMessageRef("https://ptb.discord.com/channels/@me/10/20")
+	assert.Nil(t, err)
+	assert.Equal(t, Message{ID: "20", ChannelID: "10"}, msg)
+
+	for _, ref := range []string{"20", "10/abc", "https://example.com/channels/30/10/20", "https://discord.com/channels/30/10"} {
+		_, err = ParseMessageRef(ref)
+		assert.ErrorIs(t, err, ErrorInvalidMessageRef, ref)
+	}
+}
+
+// roundTripFunc answers requests without touching the network.
+type roundTripFunc func(*http.Request) *http.Response
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req), nil
+}
+
+func respond(status int) *http.Response {
+	return respondBody(status, "")
+}
+
+func respondBody(status int, body string) *http.Response {
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+func TestDeleteForbiddenNotRecorded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	l, err := ledger.Open(path)
+	assert.Nil(t, err)
+
+	c := New("")
+	c.SetLedger(l)
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) *http.Response {
+		if strings.HasSuffix(req.URL.Path, "/messages/2") {
+			return respond(http.StatusForbidden)
+		}
+		return respond(http.StatusNoContent)
+	})
+
+	err = c.DeleteMessageList([]Message{{ID: "1", ChannelID: "10"}, {ID: "2", ChannelID: "10"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, c.DeletedCount())
+
+	kept, err := c.DeleteMessages(Messages{Messages: [][]Message{{{ID: "2", ChannelID: "10", Hit: true}}}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, kept)
+	assert.Equal(t, 1, c.DeletedCount())
+
+	assert.Nil(t, l.Close())
+	entries, err := ledger.Query(path, ledger.Filter{})
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "1", entries[0].MessageID)
+}
+
+func TestValidateCachesProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	l, err := ledger.Open(path)
+	assert.Nil(t, err)
+
+	var fetched int
+
+	c := New("")
+	c.SetLedger(l)
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) *http.Response {
+		if req.URL.Path == "/api/v10/users/@me" {
+			fetched++
+			return respondBody(http.StatusOK, `{"id": "5", "username": "alt"}`)
+		}
+		return respond(http.StatusNoContent)
+	})
+
+	me, err := c.Validate()
+	assert.Nil(t, err)
+	assert.Equal(t, "5", me.ID)
+
+	me, err = c.profile()
+	assert.Nil(t, err)
+	assert.Equal(t, "5", me.ID)
+	assert.Equal(t, 1, fetched)
+
+	assert.Nil(t, c.DeleteMessageList([]Message{{ID: "1", ChannelID: "10"}}))
+
+	assert.Nil(t, l.Close())
+	entries, err := ledger.Query(path, ledger.Filter{})
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "5", entries[0].UserID)
+}
+
+func TestDeleteSkipsThreadUnderSkippedParent(t *testing.T) {
+	c := New("")
+	assert.Nil(t, c.SetSkipChannels([]string{"10"}))
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) *http.Response {
+		t.Errorf("unexpected request %v %v", req.Method, req.URL.Path)
+		return respond(http.StatusNoContent)
+	})
+
+	kept, err := c.DeleteMessages(Messages{
+		Messages: [][]Message{{{ID: "1", ChannelID: "20", Hit: true}}},
+		Threads:  []Thread{{ID: "20", ParentID: "10"}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, kept)
+	assert.Equal(t, 0, c.DeletedCount())
+}
+
+func TestThreadPassSkipsGuildMessages(t *testing.T) {
+	page := `{"total_results": 1, "messages": [[{"id": "5", "hit": true, "channel_id": "20"}]]}`
+
+	c := New("")
+	c.SetDryRun(true)
+	c.SetSearchThreads(true)
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) *http.Response {
+		switch req.URL.Path {
+		case "/api/v10/guilds/1/messages/search":
+			// The thread is searched separately but finds the same message
+			if req.URL.Query().Get("max_id") != "" {
+				return respondBody(http.StatusOK, `{"messages": []}`)
+			}
+			return respondBody(http.StatusOK, page)
+		case "/api/v10/guilds/1/threads/active":
+			return respondBody(http.StatusOK, `{"threads": [{"id": "20", "parent_id": "10", "owner_id": "2"}]}`)
+		case "/api/v10/guilds/1/channels":
+			return respondBody(http.StatusOK, `[]`)
+		}
+		t.Errorf("unexpected request %v %v", req.Method, req.URL.Path)
+		return respond(http.StatusNotFound)
+	})
+
+	assert.Nil(t, c.DeleteFromGuild(Me{ID: "2"}, Channel{ID: "1", Name: "guild"}))
+	assert.Equal(t, 1, c.DeletedCount())
+}
+
+func TestDeleteAlreadyDeleted(t *testing.T) {
+	c := New("")
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) *http.Response {
+		return respond(http.StatusNotFound)
+	})
+
+	kept, err := c.DeleteMessages(Messages{Messages: [][]Message{{{ID: "1", ChannelID: "10", Hit: true}}}})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, kept)
+	assert.Equal(t, 0, c.DeletedCount())
+}
+
+func TestVerifyRetry(t *testing.T) {
+	verify := func(deleted func(attempt int) bool) Client {
+		var attempts int
+
+		c := New("")
+		c.SetVerify(true)
+		c.httpClient.Transport = roundTripFunc(func(req *http.Request) *http.Response {
+			if req.Method == "DELETE" {
+				attempts++
+				if deleted(attempts) {
+					return respond(http.StatusNotFound)
+				}
+				return respond(http.StatusNoContent)
+			}
+			// Search keeps finding the message
+			if req.URL.Query().Get("max_id") != "" {
+				return respondBody(http.StatusOK, `{"total_results": 0, "messages": []}`)
+			}
+			return respondBody(http.StatusOK, `{"total_results": 1, "messages": [[{"id": "1", "hit": true, "channel_id": "10"}]]}`)
+		})
+
+		search := func(before int64) (Messages, error) {
+			return c.ChannelMessages(Channel{ID: "10"}, Me{ID: "2"}, before)
+		}
+		assert.Nil(t, c.deleteFromScope("channel", "10", "channel", search))
+		return c
+	}
+
+	// The search index hasn't caught up, deleting again finds it gone
+	c := verify(func(attempt int) bool { return attempt > 1 })
+	assert.Equal(t, 1, c.DeletedCount())
+	assert.Empty(t, c.discrepancies)
+
+	// The message really is still there
+	c = verify(func(attempt int) bool { return false })
+	assert.Equal(t, 2, c.DeletedCount())
+	assert.Len(t, c.discrepancies, 1)
+}
+
+func TestThreadWritable(t *testing.T) {
+	var patched []string
+
+	c := New("")
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) *http.Response {
+		body, _ := io.ReadAll(req.Body)
+		patched = append(patched, req.URL.Path+" "+strings.TrimSpace(string(body)))
+
+		if req.URL.Path == "/api/v10/channels/30" {
+			// Not allowed, so the thread comes back unchanged
+			return respond(http.StatusForbidden)
+		}
+		return respondBody(http.StatusOK, `{"id": "20", "thread_metadata": {"archived": false}}`)
+	})
+
+	archived := func(id string) Thread {
+		thread := Thread{ID: id}
+		thread.Metadata.Archived = true
+		return thread
+	}
+	locked := archived("40")
+	locked.Metadata.Locked = true
+
+	unarchived := make(map[string]bool)
+	assert.True(t, c.threadWritable(Thread{ID: "10"}, unarchived))
+	assert.False(t, c.threadWritable(archived("20"), unarchived))
+	assert.Empty(t, patched)
+
+	c.SetUnarchiveThreads(true)
+	assert.False(t, c.threadWritable(locked, unarchived))
+	assert.True(t, c.threadWritable(archived("20"), unarchived))
+	assert.True(t, c.threadWritable(archived("20"), unarchived))
+	assert.False(t, c.threadWritable(archived("30"), unarchived))
+	assert.Equal(t, map[string]bool{"20": true, "30": false}, unarchived)
+
+	patched = nil
+	c.rearchiveThreads(unarchived)
+	assert.Equal(t, []string{`/api/v10/channels/20 {"archived":true}`}, patched)
+}
+
+func TestStopRearchivesThreads(t *testing.T) {
+	var patched []string
+
+	c := New("")
+	c.SetUnarchiveThreads(true)
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) *http.Response {
+		if req.Method == "PATCH" {
+			body, _ := io.ReadAll(req.Body)
+			patched = append(patched, strings.TrimSpace(string(body)))
+			return respondBody(http.StatusOK, `{"id": "20", "thread_metadata": {"archived": false}}`)
+		}
+		// Stop as soon as the first message is deleted
+		c.Stop()
+		return respond(http.StatusNoContent)
+	})
+
+	thread := Thread{ID: "20"}
+	thread.Metadata.Archived = true
+
+	_, err := c.DeleteMessages(Messages{
+		Messages: [][]Message{{{ID: "1", ChannelID: "20", Hit: true}}, {{ID: "2", ChannelID: "20", Hit: true}}},
+		Threads:  []Thread{thread},
+	})
+	assert.ErrorIs(t, err, ErrorQuit)
+	assert.Equal(t, 1, c.DeletedCount())
+	assert.Equal(t, []string{`{"archived":false}`, `{"archived":true}`}, patched)
+}
+
+func TestDeletePassPagination(t *testing.T) {
+	pages := map[string]string{
+		"":    `[[{"id": "300", "hit": true, "channel_id": "10"}], [{"id": "200", "hit": true, "channel_id": "10"}]]`,
+		"200": `[[{"id": "150", "hit": true, "channel_id": "10"}], [{"id": "100", "hit": true, "channel_id": "10"}]]`,
+		// The server ignored the cursor, so the pass has to stop here
+		"100": `[[{"id": "100", "hit": true, "channel_id": "10"}]]`,
+	}
+	var cursors []string
+
+	c := New("")
+	c.SetDryRun(true)
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) *http.Response {
+		cursor := req.URL.Query().Get("max_id")
+		cursors = append(cursors, cursor)
+		return respondBody(http.StatusOK, `{"messages": `+pages[cursor]+`}`)
+	})
+
+	search := func(before int64) (Messages, error) {
+		return c.ChannelMessages(Channel{ID: "10"}, Me{ID: "2"}, before)
+	}
+	kept, err := c.deletePass("channel", "channel", search)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"", "200", "100"}, cursors)
+	// Nothing is deleted on a dry run, so every message found is kept
+	assert.Equal(t, 5, kept)
+	assert.Equal(t, 5, c.DeletedCount())
+}
+
+func TestHistoryRecordsGuild(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	l, err := ledger.Open(path)
+	assert.Nil(t, err)
+
+	c := New("")
+	c.SetLedger(l)
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) *http.Response {
+		if req.Method == "GET" {
+			return respondBody(http.StatusOK, `[{"id": "1", "channel_id": "10", "author": {"id": "2"}}]`)
+		}
+		return respond(http.StatusNoContent)
+	})
+
+	assert.Nil(t, c.deleteFromHistory(Me{ID: "2"}, Channel{ID: "10"}, "5"))
+
+	assert.Nil(t, l.Close())
+	entries, err := ledger.Query(path, ledger.Filter{})
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "5", entries[0].GuildID)
+}
+
+func TestReset(t *testing.T) {
+	c := New("")
+	assert.Nil(t, c.SetOnlyChannels([]string{"Work/#general"}))
+	c.only.add("10")
+	c.guildChannels["1"] = []Channel{{ID: "10", Name: "general"}}
+	c.deletedCount = 3
+
+	c.Reset()
+	assert.False(t, c.only.Has("10"))
+	assert.Equal(t, []string{"Work/#general"}, c.only.refs)
+	assert.Empty(t, c.guildChannels)
+	assert.Equal(t, 0, c.DeletedCount())
+}
+
+func TestDataPackageLeftGroupDM(t *testing.T) {
+	var paths []string
+
+	c := New("")
+	c.SetDataPackage([]datapackage.Channel{{ID: "10", Type: GroupDirectChannel, Name: "old group"}})
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) *http.Response {
+		paths = append(paths, req.Method+" "+req.URL.Path)
+		return respond(http.StatusForbidden)
+	})
+
+	assert.Nil(t, c.DeleteFromDataPackage(Me{ID: "1"}, nil, map[string]bool{}, nil))
+	assert.Equal(t, []string{"GET /api/v10/channels/10"}, paths)
+}
+
+func TestDataPackageNotSelected(t *testing.T) {
+	c := New("")
+	assert.Nil(t, c.SetOnlyChannels([]string{"guild:Work"}))
+	c.SetDataPackage([]datapackage.Channel{
+		{ID: "10", Type: DirectChannel, Recipients: []string{"1", "2"}},
+		{ID: "11", Type: GroupDirectChannel, Name: "old group"},
+	})
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) *http.Response {
+		t.Errorf("unexpected request %v %v", req.Method, req.URL.Path)
+		return respond(http.StatusForbidden)
+	})
+
+	assert.Nil(t, c.DeleteFromDataPackage(Me{ID: "1"}, nil, map[string]bool{}, nil))
+}
+
+func TestDataPackageOpenOnly(t *testing.T) {
+	var paths []string
+
+	c := New("")
+	c.SetOpenOnly(true)
+	c.SetDataPackage([]datapackage.Channel{{ID: "10", Type: DirectChannel, Recipients: []string{"1", "2"}}})
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) *http.Response {
+		paths = append(paths, req.Method+" "+req.URL.Path)
+		return respond(http.StatusForbidden)
+	})
+
+	assert.Nil(t, c.DeleteFromDataPackage(Me{ID: "1"}, nil, map[string]bool{}, nil))
+	assert.Empty(t, paths)
+}
+
+func TestDeleteInArchivedThreadKept(t *testing.T) {
+	c := New("")
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) *http.Response {
+		return respondBody(http.StatusBadRequest, `{"message": "Thread is archived", "code": 50083}`)
+	})
+
+	kept, err := c.DeleteMessages(Messages{Messages: [][]Message{{{ID: "1", ChannelID: "10", Hit: true}}}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, kept)
+	assert.Equal(t, 0, c.DeletedCount())
+}
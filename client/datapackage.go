@@ -0,0 +1,104 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DeleteFromDataPackage deletes from channels listed in a Discord data package
+// which weren't covered by the open channels, relationships or guilds. This
+// finds DMs with users who aren't friends and whose DMs have been closed.
+func (c *Client) DeleteFromDataPackage(me Me, channels []Channel, dms map[string]bool, guilds []Channel) error {
+	seen := make(map[string]bool)
+	for _, channel := range channels {
+		seen[channel.ID] = true
+	}
+
+	joined := make(map[string]bool)
+	for _, guild := range guilds {
+		joined[guild.ID] = true
+	}
+	left := make(map[string]bool)
+
+	for _, pkgChannel := range c.dataPackage {
+		if seen[pkgChannel.ID] {
+			continue
+		}
+		seen[pkgChannel.ID] = true
+
+		switch pkgChannel.Type {
+		case DirectChannel:
+			var recipient string
+			for _, id := range pkgChannel.Recipients {
+				if id != me.ID {
+					recipient = id
+				}
+			}
+			if recipient == "" || dms[recipient] {
+				continue
+			}
+
+			// Avoid opening DMs which are going to be skipped anyway
+			if c.skipChannel(pkgChannel.ID) || !c.mayIncludeDM(Channel{ID: pkgChannel.ID}) {
+				c.log.Debugf("DM %v from data package not selected, skipping", pkgChannel.ID)
+				continue
+			}
+
+			if c.openOnly {
+				c.log.Infof("skipping user %v from data package because no DM is open with them", recipient)
+				continue
+			}
+
+			// Opening the DM again makes it searchable
+			channel, err := c.RelationshipChannel(Recipient{ID: recipient})
+			if err != nil {
+				c.log.Warnf("unable to open DM with user %v from data package: %v", recipient, err)
+				continue
+			}
+			dms[recipient] = true
+
+			c.log.Infof("resolved user %v from data package to channel %v", recipient, channel.ID)
+
+			if err = c.DeleteFromChannel(me, channel); err != nil {
+				return err
+			}
+		case GroupDirectChannel:
+			group := Channel{ID: pkgChannel.ID, Name: pkgChannel.Name}
+			if c.skipChannel(group.ID) || c.skip.MatchDM(group) || !c.mayIncludeDM(group) {
+				c.log.Debugf("group DM '%v' from data package not selected, skipping", pkgChannel.Name)
+				continue
+			}
+
+			// Group DMs we're still in were open, so this one has usually been
+			// left and its messages are out of reach
+			channel, err := c.Channel(pkgChannel.ID)
+			if errors.Is(err, ErrorForbidden) || errors.Is(err, ErrorNotFound) {
+				c.log.Warnf("no longer a member of group DM '%v', messages there can't be deleted", pkgChannel.Name)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("error fetching group DM from data package: %w", err)
+			}
+
+			if err := c.DeleteFromChannel(me, channel); err != nil {
+				return err
+			}
+
+			if c.leaveGroupDMs && c.wantChannel(channel.ID) {
+				if err := c.leaveGroupDM(channel); err != nil {
+					return err
+				}
+			}
+		default:
+			// Messages in guilds we're still in were found by the guild search
+			if pkgChannel.GuildID == "" || joined[pkgChannel.GuildID] || left[pkgChannel.GuildID] {
+				continue
+			}
+			left[pkgChannel.GuildID] = true
+
+			c.log.Warnf("no longer a member of guild '%v', messages there can't be deleted", pkgChannel.GuildName)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package datapackage
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrorNoIndex = errors.New("datapackage: messages/index.json not found")
+
+// Older packages record channel types as numbers, newer ones as names
+var channelTypes = map[string]int{
+	"GUILD_TEXT":          0,
+	"DM":                  1,
+	"GUILD_VOICE":         2,
+	"GROUP_DM":            3,
+	"GUILD_NEWS":          5,
+	"GUILD_ANNOUNCEMENT":  5,
+	"GUILD_NEWS_THREAD":   10,
+	"ANNOUNCEMENT_THREAD": 10,
+	"PUBLIC_THREAD":       11,
+	"PRIVATE_THREAD":      12,
+	"GUILD_STAGE_VOICE":   13,
+	"GUILD_FORUM":         15,
+	"GUILD_MEDIA":         16,
+}
+
+type Channel struct {
+	ID         string
+	Type       int
+	Name       string
+	GuildID    string
+	GuildName  string
+	Recipients []string
+}
+
+type channelFile struct {
+	ID         string          `json:"id"`
+	Type       json.RawMessage `json:"type"`
+	Name       string          `json:"name"`
+	Recipients []string        `json:"recipients"`
+	Guild      *struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"guild"`
+}
+
+// Open reads the channels from a data package, which may either be the zip
+// file sent by Discord or a directory it was extracted to.
+func Open(name string) ([]Channel, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, fmt.Errorf("datapackage: %w", err)
+	}
+
+	if info.IsDir() {
+		return Read(os.DirFS(name))
+	}
+
+	archive, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, fmt.Errorf("datapackage: error opening archive: %w", err)
+	}
+	defer archive.Close()
+
+	return Read(archive)
+}
+
+// Read parses every channel listed in the package's message index.
+func Read(fsys fs.FS) ([]Channel, error) {
+	root, err := messagesRoot(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := fs.ReadFile(fsys, path.Join(root, "index.json"))
+	if err != nil {
+		return nil, fmt.Errorf("datapackage: error reading index: %w", err)
+	}
+
+	var index map[string]*string
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("datapackage: error decoding index: %w", err)
+	}
+
+	ids := make([]string, 0, len(index))
+	for id := range index {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var channels []Channel
+	for _, id := range ids {
+		channel, err := readChannel(fsys, root, id)
+		if err != nil {
+			log.Debugf("skipping channel %v from data package: %v", id, err)
+			continue
+		}
+		if channel.Name == "" && index[id] != nil {
+			channel.Name = *index[id]
+		}
+
+		channels = append(channels, channel)
+	}
+
+	return channels, nil
+}
+
+func messagesRoot(fsys fs.FS) (string, error) {
+	// Accept the package itself or just its messages directory
+	for _, root := range []string{"messages", "."} {
+		if _, err := fs.Stat(fsys, path.Join(root, "index.json")); err == nil {
+			return root, nil
+		}
+	}
+	return "", ErrorNoIndex
+}
+
+func readChannel(fsys fs.FS, root string, id string) (Channel, error) {
+	var data []byte
+	var err error
+
+	// Newer packages prefix channel directories with a "c"
+	for _, dir := range []string{"c" + id, id} {
+		data, err = fs.ReadFile(fsys, path.Join(root, dir, "channel.json"))
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return Channel{}, err
+	}
+
+	var file channelFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return Channel{}, err
+	}
+
+	kind, err := channelType(file.Type)
+	if err != nil {
+		return Channel{}, err
+	}
+
+	channel := Channel{
+		ID:         id,
+		Type:       kind,
+		Name:       file.Name,
+		Recipients: file.Recipients,
+	}
+	if file.Guild != nil {
+		channel.GuildID = file.Guild.ID
+		channel.GuildName = file.Guild.Name
+	}
+
+	return channel, nil
+}
+
+func channelType(raw json.RawMessage) (int, error) {
+	var name string
+	if err := json.Unmarshal(raw, &name); err != nil {
+		return strconv.Atoi(string(raw))
+	}
+
+	kind, ok := channelTypes[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown channel type %v", name)
+	}
+	return kind, nil
+}
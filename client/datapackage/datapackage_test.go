@@ -0,0 +1,30 @@
+package datapackage
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRead(t *testing.T) {
+	fsys := fstest.MapFS{
+		"messages/index.json":      {Data: []byte(`{"1": "Direct Message with alice#0001", "2": null, "3": "general in Server", "4": null}`)},
+		"messages/c1/channel.json": {Data: []byte(`{"id": "1", "type": "DM", "recipients": ["10", "11"]}`)},
+		"messages/c2/channel.json": {Data: []byte(`{"id": "2", "type": 3, "recipients": ["10", "11", "12"]}`)},
+		"messages/3/channel.json":  {Data: []byte(`{"id": "3", "type": "GUILD_TEXT", "name": "general", "guild": {"id": "20", "name": "Server"}}`)},
+	}
+
+	channels, err := Read(fsys)
+	assert.Nil(t, err)
+	assert.Equal(t, []Channel{
+		{ID: "1", Type: 1, Name: "Direct Message with alice#0001", Recipients: []string{"10", "11"}},
+		{ID: "2", Type: 3, Recipients: []string{"10", "11", "12"}},
+		{ID: "3", Type: 0, Name: "general", GuildID: "20", GuildName: "Server"},
+	}, channels)
+}
+
+func TestReadMissingIndex(t *testing.T) {
+	_, err := Read(fstest.MapFS{})
+	assert.ErrorIs(t, err, ErrorNoIndex)
+}
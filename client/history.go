@@ -0,0 +1,114 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// DeleteFromHistory walks the channel's full message history from newest to
+// oldest instead of using search, deleting the messages authored by the user.
+// This is exact and works when the search index is unavailable, but requires a
+// request for every hundred messages in the channel. The user's reactions on
+// other people's messages are removed along the way if requested.
+func (c *Client) DeleteFromHistory(me Me, channel Channel) error {
+	return c.deleteFromHistory(me, channel, "")
+}
+
+// deleteFromHistory walks the history of a channel in the guild, or a DM if
+// guildID is empty. History doesn't say which guild messages are in.
+func (c *Client) deleteFromHistory(me Me, channel Channel, guildID string) error {
+	c.keeper = newKeepTracker(c.keepLast, c.keepWithin)
+
+	var before string
+	if c.maxID != 0 {
+		before = strconv.FormatInt(c.maxID, 10)
+	}
+
+	for {
+		if c.stopping() {
+			return ErrorQuit
+		}
+
+		page, err := c.ChannelHistory(channel, before)
+		if errors.Is(err, ErrorForbidden) {
+			c.log.Warnf("no access to channel '%v', skipping", channel.DisplayName())
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error fetching history for channel: %w", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		before = page[len(page)-1].ID
+
+		var found []Message
+		reachedMin := false
+
+		for _, msg := range page {
+			if c.minID != 0 {
+				if id, err := strconv.ParseInt(msg.ID, 10, 64); err == nil && id < c.minID {
+					reachedMin = true
+					break
+				}
+			}
+			if msg.Author.ID != me.ID {
+				if c.reactions {
+					if err := c.DeleteReactions(msg); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+
+			// Messages fetched from history are always our own at this point
+			msg.Hit = true
+			found = append(found, msg)
+		}
+
+		if len(found) > 0 {
+			results := Messages{Messages: [][]Message{found}}
+			results.setGuild(guildID)
+
+			if _, err := c.DeleteMessages(results); err != nil {
+				return err
+			}
+		}
+
+		if reachedMin || len(page) < historyLimit {
+			break
+		}
+	}
+
+	c.log.Infof("no more messages to delete for channel '%v'", channel.DisplayName())
+
+	return nil
+}
+
+// DeleteReactions removes the user's own reactions from a message.
+func (c *Client) DeleteReactions(msg Message) error {
+	for _, reaction := range msg.Reactions {
+		if !reaction.Me {
+			continue
+		}
+
+		c.log.Infof("removing reaction %v from message %v in channel %v", reaction.Emoji.Name, msg.ID, msg.ChannelID)
+		if !c.dryRun {
+			err := c.DeleteOwnReaction(msg, reaction.Emoji)
+			if errors.Is(err, ErrorForbidden) {
+				c.log.Warnf("not allowed to remove reaction from message %v, skipping", msg.ID)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("error removing reaction: %w", err)
+			}
+			time.Sleep(minSleep * time.Millisecond)
+		}
+
+		c.reactionCount++
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrorQuit = errors.New("quit by user")
+
+// Decision is the user's answer when asked whether to delete from a channel
+// or guild.
+type Decision int
+
+const (
+	DecisionDelete Decision = iota
+	DecisionSkip
+	DecisionDeleteAll
+	DecisionQuit
+)
+
+// ScopePreview describes the messages found in a channel, guild or thread so
+// that the user can decide what to do with them.
+type ScopePreview struct {
+	Kind         string
+	Name         string
+	TotalResults int
+	Samples      []Message
+}
+
+// ConfirmFunc asks the user what to do before deleting from each scope.
+type ConfirmFunc func(preview ScopePreview) Decision
+
+// SetConfirm asks for confirmation through confirm before deleting from each
+// channel, guild or thread.
+func (c *Client) SetConfirm(confirm ConfirmFunc) {
+	c.confirm = confirm
+}
+
+// confirmScope returns whether to go ahead with deleting from the scope. If the
+// user skips it, its ID is added to the skip rules so that the messages are
+// also left alone when found elsewhere, such as in a guild search.
+func (c *Client) confirmScope(kind string, id string, name string, search searchFunc) (bool, error) {
+	if c.confirm == nil || c.confirmedAll {
+		return true, nil
+	}
+
+	preview, err := c.previewScope(kind, name, search)
+	if err != nil {
+		return false, err
+	}
+	if preview.TotalResults == 0 {
+		// Nothing to ask about
+		return true, nil
+	}
+
+	switch c.confirm(preview) {
+	case DecisionSkip:
+		c.skip.add(id)
+		return false, nil
+	case DecisionDeleteAll:
+		c.confirmedAll = true
+	case DecisionQuit:
+		return false, ErrorQuit
+	}
+
+	return true, nil
+}
+
+// PreviewChannel returns the number of the user's messages in a channel and
+// the most recent of them.
+func (c *Client) PreviewChannel(me Me, channel Channel) (ScopePreview, error) {
+	search := func(before int64) (Messages, error) {
+		return c.ChannelMessages(channel, me, before)
+	}
+	return c.previewScope("channel", channel.DisplayName(), search)
+}
+
+// PreviewGuild returns the number of the user's messages in a guild and the
+// most recent of them.
+func (c *Client) PreviewGuild(me Me, guild Channel) (ScopePreview, error) {
+	search := func(before int64) (Messages, error) {
+		return c.GuildMessages(guild, me, before)
+	}
+	return c.previewScope("guild", guild.Name, search)
+}
+
+func (c *Client) previewScope(kind string, name string, search searchFunc) (ScopePreview, error) {
+	results, err := search(0)
+	if errors.Is(err, ErrorForbidden) {
+		// Nothing can be found without access, so there's nothing to ask about
+		return ScopePreview{Kind: kind, Name: name}, nil
+	}
+	if err != nil {
+		return ScopePreview{}, fmt.Errorf("error fetching messages for %v: %w", kind, err)
+	}
+
+	preview := ScopePreview{
+		Kind:         kind,
+		Name:         name,
+		TotalResults: results.TotalResults,
+	}
+	for _, ctx := range results.Messages {
+		for _, msg := range ctx {
+			if msg.Hit {
+				preview.Samples = append(preview.Samples, msg)
+			}
+		}
+	}
+
+	return preview, nil
+}
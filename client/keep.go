@@ -0,0 +1,57 @@
+package client
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/cedws/discord-delete/client/snowflake"
+)
+
+// keepTracker decides which messages to keep so that the most recent messages
+// in each channel survive. Searches and history are both ordered newest first,
+// even when a guild search mixes channels, so the first messages seen in each
+// channel are its newest.
+type keepTracker struct {
+	last   int
+	within time.Duration
+	seen   map[string]int
+	newest map[string]int64
+}
+
+func newKeepTracker(last int, within time.Duration) *keepTracker {
+	return &keepTracker{
+		last:   last,
+		within: within,
+		seen:   make(map[string]int),
+		newest: make(map[string]int64),
+	}
+}
+
+func (k *keepTracker) keep(msg Message) bool {
+	if k.last == 0 && k.within == 0 {
+		return false
+	}
+
+	var millis int64
+	if id, err := strconv.ParseInt(msg.ID, 10, 64); err == nil {
+		millis = snowflake.FromSnowflake(id)
+	}
+
+	// The window is measured from the channel's newest message, even if it is
+	// also kept by the count
+	newest, ok := k.newest[msg.ChannelID]
+	if !ok && millis != 0 {
+		newest = millis
+		k.newest[msg.ChannelID] = newest
+	}
+
+	k.seen[msg.ChannelID]++
+	if k.seen[msg.ChannelID] <= k.last {
+		return true
+	}
+
+	if k.within == 0 || millis == 0 || newest == 0 {
+		return false
+	}
+	return time.Duration(newest-millis)*time.Millisecond < k.within
+}
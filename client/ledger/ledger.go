@@ -0,0 +1,137 @@
+package ledger
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Entry records the deletion of a single message.
+type Entry struct {
+	RunID     string    `json:"run_id"`
+	UserID    string    `json:"user_id,omitempty"`
+	MessageID string    `json:"message_id"`
+	ChannelID string    `json:"channel_id"`
+	GuildID   string    `json:"guild_id,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// Ledger is an append-only JSON Lines file of deleted messages. It is safe to
+// share between clients running at the same time.
+type Ledger struct {
+	mu    sync.Mutex
+	file  *os.File
+	runID string
+}
+
+// Open opens the ledger for appending, creating it if it doesn't exist. Every
+// entry recorded through the returned ledger shares a freshly generated run ID.
+func Open(path string) (*Ledger, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("ledger: error opening file: %w", err)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("ledger: error generating run ID: %w", err)
+	}
+
+	return &Ledger{
+		file:  file,
+		runID: hex.EncodeToString(id),
+	}, nil
+}
+
+func (l *Ledger) RunID() string {
+	return l.runID
+}
+
+// Record appends an entry and syncs it to disk so that it survives a crash.
+func (l *Ledger) Record(entry Entry) error {
+	entry.RunID = l.runID
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("ledger: error encoding entry: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("ledger: error writing entry: %w", err)
+	}
+
+	return l.file.Sync()
+}
+
+func (l *Ledger) Close() error {
+	return l.file.Close()
+}
+
+// Filter selects ledger entries. Zero fields match everything.
+type Filter struct {
+	RunID     string
+	MessageID string
+	ChannelID string
+	GuildID   string
+	Since     time.Time
+	Until     time.Time
+}
+
+func (f Filter) Match(entry Entry) bool {
+	switch {
+	case f.RunID != "" && entry.RunID != f.RunID:
+		return false
+	case f.MessageID != "" && entry.MessageID != f.MessageID:
+		return false
+	case f.ChannelID != "" && entry.ChannelID != f.ChannelID:
+		return false
+	case f.GuildID != "" && entry.GuildID != f.GuildID:
+		return false
+	case !f.Since.IsZero() && entry.DeletedAt.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && entry.DeletedAt.After(f.Until):
+		return false
+	}
+	return true
+}
+
+// Query returns the entries in the ledger at path which match the filter.
+func Query(path string, filter Filter) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("ledger: error opening file: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("ledger: error decoding line %v: %w", line, err)
+		}
+		if filter.Match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ledger: error reading file: %w", err)
+	}
+
+	return entries, nil
+}
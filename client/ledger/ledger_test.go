@@ -0,0 +1,34 @@
+package ledger
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordAndQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+
+	l, err := Open(path)
+	assert.Nil(t, err)
+	assert.Nil(t, l.Record(Entry{MessageID: "1", ChannelID: "10", DeletedAt: time.Unix(100, 0)}))
+	assert.Nil(t, l.Record(Entry{MessageID: "2", ChannelID: "20", GuildID: "30", DeletedAt: time.Unix(200, 0)}))
+	assert.Nil(t, l.Close())
+
+	entries, err := Query(path, Filter{})
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, l.RunID(), entries[0].RunID)
+
+	entries, err = Query(path, Filter{GuildID: "30"})
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "2", entries[0].MessageID)
+
+	entries, err = Query(path, Filter{Until: time.Unix(150, 0)})
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "1", entries[0].MessageID)
+}
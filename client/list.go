@@ -0,0 +1,90 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var ErrorInvalidMessageRef = errors.New("message must be a channel_id/message_id pair or a message link")
+
+// DeleteMessageList deletes exactly the given messages without searching for
+// them. None of the filters used while searching are applied, so each message
+// only needs its ID and channel ID. Messages which no longer exist or which
+// the user isn't allowed to delete are skipped.
+func (c *Client) DeleteMessageList(messages []Message) error {
+	skipped := 0
+
+	for _, msg := range messages {
+		err := c.deleteMessage(msg)
+		switch {
+		case errors.Is(err, ErrorNotFound):
+			c.log.Warnf("message %v in channel %v no longer exists, skipping", msg.ID, msg.ChannelID)
+			skipped++
+		case errors.Is(err, ErrorForbidden):
+			c.log.Warnf("not allowed to delete message %v in channel %v, skipping", msg.ID, msg.ChannelID)
+			skipped++
+		case threadArchived(err):
+			c.log.Warnf("message %v is in archived or locked thread %v, skipping", msg.ID, msg.ChannelID)
+			skipped++
+		case err != nil:
+			return err
+		}
+	}
+
+	c.log.Infof("finished deleting messages: %v deleted and %v skipped in %v total requests", c.deletedCount, skipped, c.requestCount)
+
+	return nil
+}
+
+// Hosts which message links are copied from
+var linkHosts = map[string]bool{
+	"discord.com":        true,
+	"ptb.discord.com":    true,
+	"canary.discord.com": true,
+	"discordapp.com":     true,
+}
+
+// ParseMessageRef parses a reference to a single message, either as a
+// channel_id/message_id pair or a message link copied from the Discord client
+// such as https://discord.com/channels/guild_id/channel_id/message_id.
+func ParseMessageRef(ref string) (Message, error) {
+	invalid := fmt.Errorf("%w: %v", ErrorInvalidMessageRef, ref)
+
+	var msg Message
+	parts := strings.Split(ref, "/")
+
+	if strings.Contains(ref, "://") {
+		u, err := url.Parse(ref)
+		if err != nil || !linkHosts[u.Hostname()] {
+			return Message{}, invalid
+		}
+
+		parts = strings.Split(strings.Trim(u.Path, "/"), "/")
+		if len(parts) != 4 || parts[0] != "channels" {
+			return Message{}, invalid
+		}
+		// DMs are linked with @me in place of the guild
+		if parts[1] != "@me" {
+			if !isSnowflake(parts[1]) {
+				return Message{}, invalid
+			}
+			msg.GuildID = parts[1]
+		}
+		parts = parts[2:]
+	}
+
+	if len(parts) != 2 || !isSnowflake(parts[0]) || !isSnowflake(parts[1]) {
+		return Message{}, invalid
+	}
+	msg.ChannelID, msg.ID = parts[0], parts[1]
+
+	return msg, nil
+}
+
+func isSnowflake(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 64)
+	return err == nil
+}
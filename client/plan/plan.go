@@ -0,0 +1,59 @@
+package plan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+var ErrorNoUser = errors.New("plan: no user ID")
+
+// Message is a single message which the plan will delete.
+type Message struct {
+	ID        string    `json:"id"`
+	ChannelID string    `json:"channel_id"`
+	GuildID   string    `json:"guild_id,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Preview   string    `json:"preview"`
+}
+
+// Plan is the exact set of messages a run would delete, saved so that it can
+// be reviewed before anything is deleted.
+type Plan struct {
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+	Messages  []Message `json:"messages"`
+}
+
+// Save writes the plan to path as indented JSON, replacing any existing file.
+func (p Plan) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("plan: error encoding plan: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("plan: error writing file: %w", err)
+	}
+	return nil
+}
+
+// Load reads a plan written by Save.
+func Load(path string) (Plan, error) {
+	var p Plan
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return p, fmt.Errorf("plan: error reading file: %w", err)
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, fmt.Errorf("plan: error decoding plan: %w", err)
+	}
+	if p.UserID == "" {
+		return p, ErrorNoUser
+	}
+
+	return p, nil
+}
@@ -0,0 +1,37 @@
+package plan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+
+	p := Plan{
+		UserID:    "1",
+		Username:  "user",
+		CreatedAt: time.Unix(100, 0).UTC(),
+		Messages: []Message{
+			{ID: "2", ChannelID: "10", Timestamp: time.Unix(50, 0).UTC(), Preview: "hello"},
+			{ID: "3", ChannelID: "20", GuildID: "30", Timestamp: time.Unix(60, 0).UTC()},
+		},
+	}
+	assert.Nil(t, p.Save(path))
+
+	loaded, err := Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, p, loaded)
+}
+
+func TestLoadNoUser(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	assert.Nil(t, os.WriteFile(path, []byte(`{"messages": []}`), 0o600))
+
+	_, err := Load(path)
+	assert.ErrorIs(t, err, ErrorNoUser)
+}
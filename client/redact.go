@@ -0,0 +1,83 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Redaction records a message whose content was partially rewritten. Hashes
+// are kept rather than the content itself so the report doesn't leak secrets.
+type Redaction struct {
+	MessageID  string `json:"message_id"`
+	ChannelID  string `json:"channel_id"`
+	GuildID    string `json:"guild_id,omitempty"`
+	Matches    int    `json:"matches"`
+	BeforeHash string `json:"before_sha256"`
+	AfterHash  string `json:"after_sha256"`
+}
+
+// SetRedact rewrites the parts of messages matching pattern with replacement
+// instead of deleting them. Messages which don't match are left alone.
+func (c *Client) SetRedact(pattern *regexp.Regexp, replacement string) {
+	c.redactPattern = pattern
+	c.redactReplacement = replacement
+}
+
+func (c *Client) Redactions() []Redaction {
+	return c.redactions
+}
+
+func (c *Client) redactMessage(msg Message) error {
+	if !editable(msg) {
+		return nil
+	}
+
+	matches := c.redactPattern.FindAllStringIndex(msg.Content, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	content := c.redactPattern.ReplaceAllString(msg.Content, c.redactReplacement)
+	if content == msg.Content {
+		// Already redacted on a previous run
+		return nil
+	}
+
+	c.log.Infof("redacting %v matches in message %v from channel %v", len(matches), msg.ID, msg.ChannelID)
+	if !c.dryRun {
+		edit := MessageEdit{
+			Content: content,
+			Embeds:  []any{},
+			Flags:   msg.Flags | SuppressEmbedsFlag,
+		}
+		_, err := c.EditMessage(msg, edit)
+		if errors.Is(err, ErrorForbidden) {
+			c.log.Warnf("not allowed to redact message %v in channel %v, skipping", msg.ID, msg.ChannelID)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error redacting message: %w", err)
+		}
+		time.Sleep(minSleep * time.Millisecond)
+	}
+
+	c.redactions = append(c.redactions, Redaction{
+		MessageID:  msg.ID,
+		ChannelID:  msg.ChannelID,
+		GuildID:    msg.GuildID,
+		Matches:    len(matches),
+		BeforeHash: hashContent(msg.Content),
+		AfterHash:  hashContent(content),
+	})
+
+	return nil
+}
+
+func hashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
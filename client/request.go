@@ -3,26 +3,51 @@ package client
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
-
-	log "github.com/sirupsen/logrus"
 )
 
 const (
 	api          = "https://discord.com/api/v10"
 	messageLimit = 25
+	threadLimit  = 100
+	historyLimit = 100
 )
 
+// Error code returned when changing anything in an archived or locked thread
+const threadArchivedCode = 50083
+
+// APIError is a bad request rejected by the server, with the reason it gave.
+type APIError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("bad status code %v: %v (code %v)", http.StatusText(http.StatusBadRequest), e.Message, e.Code)
+}
+
+// threadArchived reports whether the request failed because the message is in
+// an archived or locked thread.
+func threadArchived(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == threadArchivedCode
+}
+
 type RequestArgs struct {
 	IncludeNSFW bool
 	AuthorID    string
+	ChannelID   string
 	MinID       int64
 	MaxID       int64
-	Offset      int
+	Before      string
+	SortBy      string
+	SortOrder   string
 	Limit       int
 }
 
@@ -34,14 +59,23 @@ func (r RequestArgs) MarshalText() string {
 	if r.AuthorID != "" {
 		args = append(args, fmt.Sprintf("author_id=%v", r.AuthorID))
 	}
+	if r.ChannelID != "" {
+		args = append(args, fmt.Sprintf("channel_id=%v", r.ChannelID))
+	}
 	if r.MinID != 0 {
 		args = append(args, fmt.Sprintf("min_id=%v", r.MinID))
 	}
 	if r.MaxID != 0 {
 		args = append(args, fmt.Sprintf("max_id=%v", r.MaxID))
 	}
-	if r.Offset != 0 {
-		args = append(args, fmt.Sprintf("offset=%v", r.Offset))
+	if r.Before != "" {
+		args = append(args, fmt.Sprintf("before=%v", url.QueryEscape(r.Before)))
+	}
+	if r.SortBy != "" {
+		args = append(args, fmt.Sprintf("sort_by=%v", r.SortBy))
+	}
+	if r.SortOrder != "" {
+		args = append(args, fmt.Sprintf("sort_order=%v", r.SortOrder))
 	}
 	if r.Limit != 0 {
 		args = append(args, fmt.Sprintf("limit=%v", r.Limit))
@@ -51,8 +85,8 @@ func (r RequestArgs) MarshalText() string {
 }
 
 func (c *Client) request(method string, endpoint string, reqData any, resData any) error {
-	url := api + endpoint
-	log.Debugf("%v %v", method, url)
+	reqURL := api + endpoint
+	c.log.Debugf("%v %v", method, reqURL)
 
 	buffer := new(bytes.Buffer)
 	if reqData != nil {
@@ -60,7 +94,7 @@ func (c *Client) request(method string, endpoint string, reqData any, resData an
 			return fmt.Errorf("error encoding request data: %w", err)
 		}
 	}
-	req, err := http.NewRequest(method, url, buffer)
+	req, err := http.NewRequest(method, reqURL, buffer)
 	if err != nil {
 		return fmt.Errorf("error building request: %w", err)
 	}
@@ -77,7 +111,7 @@ func (c *Client) request(method string, endpoint string, reqData any, resData an
 
 	c.requestCount++
 
-	log.Debugf("server returned status %v", http.StatusText(res.StatusCode))
+	c.log.Debugf("server returned status %v", http.StatusText(res.StatusCode))
 
 	switch status := res.StatusCode; {
 	case status >= http.StatusInternalServerError:
@@ -97,11 +131,17 @@ func (c *Client) request(method string, endpoint string, reqData any, resData an
 		// Try again once we've waited for the period that the server has asked us to.
 		return c.request(method, endpoint, reqData, resData)
 	case status == http.StatusForbidden:
-		break
+		return ErrorForbidden
 	case status == http.StatusUnauthorized:
-		return fmt.Errorf("bad status code %v, log out and log back in to discord or verify your token is correct", http.StatusText(res.StatusCode))
+		return fmt.Errorf("%w with status code %v, log out and log back in to discord or verify your token is correct", ErrorInvalidToken, http.StatusText(res.StatusCode))
+	case status == http.StatusNotFound:
+		return ErrorNotFound
 	case status == http.StatusBadRequest:
-		return fmt.Errorf("bad status code %v", http.StatusText(res.StatusCode))
+		apiErr := &APIError{}
+		if err := json.NewDecoder(res.Body).Decode(apiErr); err != nil {
+			return fmt.Errorf("bad status code %v", http.StatusText(res.StatusCode))
+		}
+		return apiErr
 	case status == http.StatusNoContent:
 		break
 	case status == http.StatusOK:
@@ -116,6 +156,27 @@ func (c *Client) request(method string, endpoint string, reqData any, resData an
 	return err
 }
 
+// searchArgs builds the arguments for a search of the user's messages. Results
+// are paginated by moving the maximum ID down to before, the oldest message
+// seen so far, rather than by offset.
+func (c *Client) searchArgs(me Me, before int64) RequestArgs {
+	maxID := c.maxID
+	if before != 0 && (maxID == 0 || before < maxID) {
+		maxID = before
+	}
+
+	return RequestArgs{
+		IncludeNSFW: true,
+		AuthorID:    me.ID,
+		Limit:       messageLimit,
+		MinID:       c.minID,
+		MaxID:       maxID,
+		// Pagination and keeping recent messages both rely on newest first
+		SortBy:    "timestamp",
+		SortOrder: "desc",
+	}
+}
+
 func (c *Client) wait(res *http.Response, mult int) error {
 	data := new(ServerWait)
 	if err := json.NewDecoder(res.Body).Decode(data); err != nil {
@@ -123,7 +184,7 @@ func (c *Client) wait(res *http.Response, mult int) error {
 	}
 
 	millis := time.Duration(data.RetryAfter*float32(mult)) * time.Millisecond
-	log.Infof("server asked us to sleep for %v", millis)
+	c.log.Infof("server asked us to sleep for %v", millis)
 	time.Sleep(millis)
 
 	return nil
@@ -139,34 +200,106 @@ func (c *Client) DeleteMessage(msg Message) (err error) {
 	return
 }
 
+func (c *Client) EditMessage(msg Message, edit MessageEdit) (edited Message, err error) {
+	endpoint := fmt.Sprintf(
+		"/channels/%v/messages/%v",
+		msg.ChannelID,
+		msg.ID,
+	)
+	err = c.request("PATCH", endpoint, edit, &edited)
+	return
+}
+
+func (c *Client) DeleteOwnReaction(msg Message, emoji Emoji) (err error) {
+	endpoint := fmt.Sprintf(
+		"/channels/%v/messages/%v/reactions/%v/@me",
+		msg.ChannelID,
+		msg.ID,
+		url.PathEscape(emoji.APIName()),
+	)
+	err = c.request("DELETE", endpoint, nil, nil)
+	return
+}
+
+func (c *Client) SetThreadArchived(thread Thread, archived bool) (updated Thread, err error) {
+	endpoint := fmt.Sprintf(
+		"/channels/%v",
+		thread.ID,
+	)
+	state := struct {
+		Archived bool `json:"archived"`
+	}{
+		archived,
+	}
+
+	err = c.request("PATCH", endpoint, state, &updated)
+	return
+}
+
 func (c *Client) Me() (me Me, err error) {
 	err = c.request("GET", "/users/@me", nil, &me)
 	return
 }
 
+// Validate checks that the token is accepted by the server, returning the user
+// it belongs to.
+func (c *Client) Validate() (Me, error) {
+	me, err := c.Me()
+	if errors.Is(err, ErrorForbidden) || err == nil && me.ID == "" {
+		return Me{}, ErrorInvalidToken
+	}
+	if err != nil {
+		return Me{}, err
+	}
+	c.me = me
+	return me, nil
+}
+
+// profile returns the user the token belongs to, only fetching it if the token
+// hasn't been validated already.
+func (c *Client) profile() (Me, error) {
+	if c.me.ID != "" {
+		return c.me, nil
+	}
+
+	me, err := c.Me()
+	if err != nil {
+		return Me{}, err
+	}
+	c.me = me
+	return me, nil
+}
+
 func (c *Client) Channels() (channels []Channel, err error) {
 	err = c.request("GET", "/users/@me/channels", nil, &channels)
 	return
 }
 
-func (c *Client) ChannelMessages(channel Channel, me Me, offset int) (messages Messages, err error) {
+func (c *Client) ChannelMessages(channel Channel, me Me, before int64) (messages Messages, err error) {
 	endpoint := fmt.Sprintf(
 		"/channels/%v/messages/search",
 		channel.ID,
 	)
-	args := RequestArgs{
-		IncludeNSFW: true,
-		AuthorID:    me.ID,
-		Offset:      offset,
-		Limit:       messageLimit,
-		MinID:       c.minID,
-		MaxID:       c.maxID,
-	}
+	args := c.searchArgs(me, before)
 
 	err = c.request("GET", endpoint+args.MarshalText(), nil, &messages)
 	return
 }
 
+func (c *Client) Channel(id string) (channel Channel, err error) {
+	err = c.request("GET", "/channels/"+id, nil, &channel)
+	return
+}
+
+func (c *Client) LeaveChannel(channel Channel) (err error) {
+	endpoint := fmt.Sprintf(
+		"/channels/%v?silent=true",
+		channel.ID,
+	)
+	err = c.request("DELETE", endpoint, nil, nil)
+	return
+}
+
 func (c *Client) RelationshipChannel(relation Recipient) (channel Channel, err error) {
 	recipients := struct {
 		Recipients []string `json:"recipients"`
@@ -188,18 +321,89 @@ func (c *Client) Guilds() (channels []Channel, err error) {
 	return
 }
 
-func (c *Client) GuildMessages(channel Channel, me Me, offset int) (messages Messages, err error) {
+func (c *Client) GuildMessages(channel Channel, me Me, before int64) (messages Messages, err error) {
 	endpoint := fmt.Sprintf(
 		"/guilds/%v/messages/search",
 		channel.ID,
 	)
+	args := c.searchArgs(me, before)
+
+	err = c.request("GET", endpoint+args.MarshalText(), nil, &messages)
+	return
+}
+
+func (c *Client) GuildChannels(guild Channel) (channels []Channel, err error) {
+	endpoint := fmt.Sprintf(
+		"/guilds/%v/channels",
+		guild.ID,
+	)
+	err = c.request("GET", endpoint, nil, &channels)
+	return
+}
+
+func (c *Client) ActiveThreads(guild Channel) (threads Threads, err error) {
+	endpoint := fmt.Sprintf(
+		"/guilds/%v/threads/active",
+		guild.ID,
+	)
+	err = c.request("GET", endpoint, nil, &threads)
+	return
+}
+
+func (c *Client) ArchivedPublicThreads(channel Channel, before string) (threads Threads, err error) {
+	endpoint := fmt.Sprintf(
+		"/channels/%v/threads/archived/public",
+		channel.ID,
+	)
 	args := RequestArgs{
-		IncludeNSFW: true,
-		AuthorID:    me.ID,
-		Offset:      offset,
-		Limit:       messageLimit,
-		MinID:       c.minID,
-		MaxID:       c.maxID,
+		Before: before,
+		Limit:  threadLimit,
+	}
+
+	err = c.request("GET", endpoint+args.MarshalText(), nil, &threads)
+	return
+}
+
+func (c *Client) ArchivedPrivateThreads(channel Channel, before string) (threads Threads, err error) {
+	// Listing every private thread requires MANAGE_THREADS, but we're only
+	// interested in the ones we've joined anyway
+	endpoint := fmt.Sprintf(
+		"/channels/%v/users/@me/threads/archived/private",
+		channel.ID,
+	)
+	args := RequestArgs{
+		Before: before,
+		Limit:  threadLimit,
+	}
+
+	err = c.request("GET", endpoint+args.MarshalText(), nil, &threads)
+	return
+}
+
+func (c *Client) GuildChannelMessages(guild Channel, channel Channel, me Me, before int64) (messages Messages, err error) {
+	endpoint := fmt.Sprintf(
+		"/guilds/%v/messages/search",
+		guild.ID,
+	)
+	args := c.searchArgs(me, before)
+	args.ChannelID = channel.ID
+
+	err = c.request("GET", endpoint+args.MarshalText(), nil, &messages)
+	return
+}
+
+func (c *Client) ThreadMessages(guild Channel, thread Thread, me Me, before int64) (messages Messages, err error) {
+	return c.GuildChannelMessages(guild, Channel{ID: thread.ID}, me, before)
+}
+
+func (c *Client) ChannelHistory(channel Channel, before string) (messages []Message, err error) {
+	endpoint := fmt.Sprintf(
+		"/channels/%v/messages",
+		channel.ID,
+	)
+	args := RequestArgs{
+		Before: before,
+		Limit:  historyLimit,
 	}
 
 	err = c.request("GET", endpoint+args.MarshalText(), nil, &messages)
@@ -14,3 +14,12 @@ func TestMarshalRequestArgs(t *testing.T) {
 	}
 	assert.Equal(t, "?include_nsfw=true&author_id=12345&limit=25", args.MarshalText())
 }
+
+func TestMarshalRequestArgsChannel(t *testing.T) {
+	args := RequestArgs{
+		AuthorID:  "12345",
+		ChannelID: "67890",
+		Before:    "2023-01-01T00:00:00+00:00",
+	}
+	assert.Equal(t, "?author_id=12345&channel_id=67890&before=2023-01-01T00%3A00%3A00%2B00%3A00", args.MarshalText())
+}
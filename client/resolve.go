@@ -0,0 +1,134 @@
+package client
+
+import (
+	"errors"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// channelRef refers to a guild channel or category by name rather than ID,
+// written as guild/#channel or guild/category.
+type channelRef struct {
+	guild    string
+	name     string
+	category bool
+}
+
+func parseChannelRef(value string) (channelRef, bool) {
+	guild, name, ok := strings.Cut(value, "/")
+	if !ok || guild == "" || name == "" {
+		return channelRef{}, false
+	}
+
+	if channel := strings.TrimPrefix(name, "#"); channel != name {
+		return channelRef{guild: guild, name: channel}, true
+	}
+	return channelRef{guild: guild, name: name, category: true}, true
+}
+
+// resolveChannelRefs returns the IDs of the channels in the guild which the
+// references point to. A category resolves to itself and every channel in it.
+func resolveChannelRefs(values []string, guild Channel, channels []Channel) []string {
+	var ids []string
+
+	for _, value := range values {
+		ref, ok := parseChannelRef(value)
+		if !ok || !strings.EqualFold(ref.guild, guild.Name) {
+			continue
+		}
+
+		resolved := false
+		for _, channel := range channels {
+			if !strings.EqualFold(channel.Name, ref.name) || (channel.Type == GuildCategoryChannel) != ref.category {
+				continue
+			}
+			resolved = true
+			ids = append(ids, channel.ID)
+
+			if !ref.category {
+				continue
+			}
+			for _, child := range channels {
+				if child.ParentID == channel.ID {
+					ids = append(ids, child.ID)
+				}
+			}
+		}
+
+		if !resolved {
+			log.Warnf("unable to find '%v' in guild '%v'", ref.name, guild.Name)
+		}
+	}
+
+	return ids
+}
+
+// hasChannelRefs reports whether any of the references are for channels in the
+// guild, so that we only list its channels when necessary.
+func hasChannelRefs(values []string, guild Channel) bool {
+	for _, value := range values {
+		if ref, ok := parseChannelRef(value); ok && strings.EqualFold(ref.guild, guild.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveGuild adds the IDs of the guild and any channels in it which are
+// matched by name in the skip or only rules.
+func (c *Client) resolveGuild(guild Channel) error {
+	for _, rules := range []Rules{c.skip, c.only} {
+		if rules.MatchGuild(guild) {
+			rules.add(guild.ID)
+		}
+	}
+
+	if len(c.skip.channels) == 0 && len(c.only.channels) == 0 &&
+		!hasChannelRefs(c.skip.refs, guild) && !hasChannelRefs(c.only.refs, guild) {
+		return nil
+	}
+
+	channels, err := c.guildChannelList(guild)
+	if err != nil {
+		return err
+	}
+
+	for _, rules := range []Rules{c.skip, c.only} {
+		rules.add(resolveChannelRefs(rules.refs, guild, channels)...)
+
+		for _, channel := range channels {
+			if rules.MatchGuildChannel(channel) {
+				rules.add(channel.ID)
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolveDM adds the ID of a DM or group DM if it's matched by name in the
+// skip or only rules.
+func (c *Client) resolveDM(channel Channel) {
+	for _, rules := range []Rules{c.skip, c.only} {
+		if rules.MatchDM(channel) {
+			rules.add(channel.ID)
+		}
+	}
+}
+
+// guildChannelList lists the channels in a guild, caching them for the rest
+// of the run.
+func (c *Client) guildChannelList(guild Channel) ([]Channel, error) {
+	if channels, ok := c.guildChannels[guild.ID]; ok {
+		return channels, nil
+	}
+
+	channels, err := c.GuildChannels(guild)
+	if err != nil && !errors.Is(err, ErrorForbidden) {
+		return nil, err
+	}
+	c.guildChannels[guild.ID] = channels
+
+	return channels, nil
+}
@@ -0,0 +1,157 @@
+package client
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Prefixes for rules which match by name rather than ID
+const (
+	guildRulePrefix   = "guild:"
+	channelRulePrefix = "channel:"
+	dmRulePrefix      = "dm:"
+)
+
+// matcher reports whether a guild, channel or user name matches a rule.
+type matcher func(name string) bool
+
+// Rules select channels and guilds either by ID or by name. Names are matched
+// with globs, or regular expressions when wrapped in slashes, and resolved to
+// IDs as guilds, channels and DMs are found.
+type Rules struct {
+	ids      map[string]bool
+	guilds   []matcher
+	channels []matcher
+	dms      []matcher
+	refs     []string
+}
+
+func ParseRules(values []string) (Rules, error) {
+	rules := Rules{
+		ids: make(map[string]bool),
+	}
+
+	for _, value := range values {
+		var list *[]matcher
+		var pattern string
+
+		switch {
+		case strings.HasPrefix(value, guildRulePrefix):
+			list, pattern = &rules.guilds, strings.TrimPrefix(value, guildRulePrefix)
+		case strings.HasPrefix(value, channelRulePrefix):
+			list, pattern = &rules.channels, strings.TrimPrefix(value, channelRulePrefix)
+		case strings.HasPrefix(value, dmRulePrefix):
+			list, pattern = &rules.dms, strings.TrimPrefix(value, dmRulePrefix)
+		case strings.Contains(value, "/"):
+			rules.refs = append(rules.refs, value)
+			continue
+		default:
+			rules.ids[value] = true
+			continue
+		}
+
+		match, err := parsePattern(pattern)
+		if err != nil {
+			return Rules{}, fmt.Errorf("error parsing rule %v: %w", value, err)
+		}
+		*list = append(*list, match)
+	}
+
+	return rules, nil
+}
+
+func parsePattern(pattern string) (matcher, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	}
+
+	pattern = strings.ToLower(pattern)
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	return func(name string) bool {
+		ok, _ := path.Match(pattern, strings.ToLower(name))
+		return ok
+	}, nil
+}
+
+func (r Rules) Empty() bool {
+	return len(r.ids) == 0 && len(r.guilds) == 0 && len(r.channels) == 0 && len(r.dms) == 0 && len(r.refs) == 0
+}
+
+func (r Rules) Has(id string) bool {
+	return r.ids[id]
+}
+
+func (r Rules) add(ids ...string) {
+	for _, id := range ids {
+		r.ids[id] = true
+	}
+}
+
+// MatchGuild reports whether the guild is matched by name.
+func (r Rules) MatchGuild(guild Channel) bool {
+	return matchAny(r.guilds, guild.Name)
+}
+
+// MatchGuildChannel reports whether a channel in a guild is matched by name.
+func (r Rules) MatchGuildChannel(channel Channel) bool {
+	return matchAny(r.channels, channel.Name)
+}
+
+// MatchDM reports whether a DM is matched by the username of a recipient, or
+// the name of a group DM.
+func (r Rules) MatchDM(channel Channel) bool {
+	if channel.Name != "" && matchAny(r.dms, channel.Name) {
+		return true
+	}
+	for _, recipient := range channel.Recipients {
+		if matchAny(r.dms, recipient.Username) {
+			return true
+		}
+	}
+	return false
+}
+
+// mayMatchInGuild reports whether listing a guild's channels could resolve
+// any of the rules. IDs which are known to be DMs or guilds can't be channels
+// inside the guild.
+func (r Rules) mayMatchInGuild(guild Channel, known map[string]bool) bool {
+	for id := range r.ids {
+		if !known[id] {
+			return true
+		}
+	}
+	return len(r.channels) > 0 || hasChannelRefs(r.refs, guild)
+}
+
+// mayMatchDM reports whether a DM which isn't open could be selected by the
+// rules. IDs which are known to be open channels or guilds can't be it, and
+// when the recipients aren't known yet any DM rule might match once it's open.
+func (r Rules) mayMatchDM(dm Channel, known map[string]bool) bool {
+	for id := range r.ids {
+		if !known[id] {
+			return true
+		}
+	}
+	if dm.Name == "" && len(dm.Recipients) == 0 {
+		return len(r.dms) > 0
+	}
+	return r.MatchDM(dm)
+}
+
+func matchAny(matchers []matcher, name string) bool {
+	for _, match := range matchers {
+		if match(name) {
+			return true
+		}
+	}
+	return false
+}
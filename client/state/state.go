@@ -0,0 +1,92 @@
+// Package state persists the progress of the retention daemon so that each
+// pass only looks at messages which the previous passes haven't.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cedws/discord-delete/client/snowflake"
+)
+
+// State is the progress of the daemon for one account.
+type State struct {
+	UserID string `json:"user_id"`
+	// HighWater is the snowflake which every message older than has already
+	// been dealt with
+	HighWater int64     `json:"high_water,string"`
+	LastRun   time.Time `json:"last_run"`
+}
+
+// Window returns the snowflakes between which a pass at now searches, to find
+// messages older than olderThan which earlier passes haven't dealt with. The
+// bounds are exclusive, and ok is false if no messages have passed the age
+// since the last pass.
+func (s State) Window(now time.Time, olderThan time.Duration) (minID int64, maxID int64, ok bool) {
+	maxID = snowflake.ToSnowflake(now.Add(-olderThan).UnixMilli())
+	if maxID <= s.HighWater {
+		return 0, 0, false
+	}
+
+	if s.HighWater != 0 {
+		// A message may have exactly the high-water ID, so search from just
+		// below it
+		minID = s.HighWater - 1
+	}
+	return minID, maxID, true
+}
+
+// Load reads the state at path. A missing file gives the zero state, from
+// which the first pass covers every message.
+func Load(path string) (State, error) {
+	var s State
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("state: error reading file: %w", err)
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("state: error decoding file: %w", err)
+	}
+
+	return s, nil
+}
+
+// Save writes the state to path. The file is replaced in one step so that a
+// crash never leaves it half written.
+func (s State) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("state: error encoding state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".state-*")
+	if err != nil {
+		return fmt.Errorf("state: error creating file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("state: error writing file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("state: error writing file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("state: error writing file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("state: error replacing file: %w", err)
+	}
+
+	return nil
+}
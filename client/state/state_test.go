@@ -0,0 +1,48 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cedws/discord-delete/client/snowflake"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.json")
+
+	s, err := Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, State{}, s)
+
+	s = State{
+		UserID:    "1",
+		HighWater: 838188033638400000,
+		LastRun:   time.Unix(100, 0).UTC(),
+	}
+	assert.Nil(t, s.Save(path))
+
+	loaded, err := Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, s, loaded)
+}
+
+func TestWindow(t *testing.T) {
+	now := time.UnixMilli(1619910000000)
+	cutoff := snowflake.ToSnowflake(now.Add(-30 * 24 * time.Hour).UnixMilli())
+
+	minID, maxID, ok := State{}.Window(now, 30*24*time.Hour)
+	assert.True(t, ok)
+	assert.Equal(t, int64(0), minID)
+	assert.Equal(t, cutoff, maxID)
+
+	minID, maxID, ok = State{HighWater: cutoff - 100}.Window(now, 30*24*time.Hour)
+	assert.True(t, ok)
+	assert.Equal(t, cutoff-101, minID)
+	assert.Equal(t, cutoff, maxID)
+
+	_, _, ok = State{HighWater: cutoff}.Window(now, 30*24*time.Hour)
+	assert.False(t, ok)
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DeleteFromThreads searches each thread in the guild that the user took part
+// in individually. Guild searches only find thread messages incidentally, so
+// this catches anything that was missed because results were capped or stale.
+func (c *Client) DeleteFromThreads(me Me, guild Channel) error {
+	threads, err := c.GuildThreads(me, guild)
+	if err != nil {
+		return fmt.Errorf("error fetching threads for guild: %w", err)
+	}
+
+	for _, thread := range threads {
+		if err = c.DeleteFromThread(me, guild, thread); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) DeleteFromThread(me Me, guild Channel, thread Thread) error {
+	if c.skipChannel(thread.ID) || c.skipChannel(thread.ParentID) {
+		c.log.Infof("skipping message deletion for thread '%v'", thread.Name)
+		return nil
+	}
+
+	search := func(before int64) (Messages, error) {
+		results, err := c.ThreadMessages(guild, thread, me, before)
+		results.setGuild(guild.ID)
+		results.markHandled(c.guildMessages)
+		return results, err
+	}
+
+	return c.deleteFromScope("thread", thread.ID, thread.Name, search)
+}
+
+// GuildThreads lists the active and archived threads in a guild, including
+// forum posts, that the user created or joined.
+func (c *Client) GuildThreads(me Me, guild Channel) ([]Thread, error) {
+	var found []Thread
+	seen := make(map[string]bool)
+
+	collect := func(threads Threads) {
+		joined := make(map[string]bool)
+		for _, member := range threads.Members {
+			joined[member.ID] = true
+		}
+
+		for _, thread := range threads.Threads {
+			if seen[thread.ID] {
+				continue
+			}
+			if !joined[thread.ID] && thread.OwnerID != me.ID {
+				continue
+			}
+
+			seen[thread.ID] = true
+			found = append(found, thread)
+		}
+	}
+
+	active, err := c.ActiveThreads(guild)
+	if err != nil && !errors.Is(err, ErrorForbidden) {
+		return nil, err
+	}
+	collect(active)
+
+	channels, err := c.guildChannelList(guild)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, channel := range channels {
+		switch channel.Type {
+		case GuildTextChannel, GuildAnnouncementChannel, GuildForumChannel, GuildMediaChannel:
+		default:
+			continue
+		}
+
+		listings := []struct {
+			list func(Channel, string) (Threads, error)
+			// Public archives are paginated by archive time, joined private
+			// archives by thread ID
+			cursor func(Thread) string
+		}{
+			{c.ArchivedPublicThreads, func(t Thread) string { return t.Metadata.ArchiveTimestamp }},
+			{c.ArchivedPrivateThreads, func(t Thread) string { return t.ID }},
+		}
+
+		for _, listing := range listings {
+			before := ""
+
+			for {
+				threads, err := listing.list(channel, before)
+				if errors.Is(err, ErrorForbidden) {
+					// Archives in channels we can't read are left out
+					break
+				}
+				if err != nil {
+					return nil, err
+				}
+				collect(threads)
+
+				if !threads.HasMore || len(threads.Threads) == 0 {
+					break
+				}
+				before = listing.cursor(threads.Threads[len(threads.Threads)-1])
+			}
+		}
+	}
+
+	c.log.Debugf("found %v threads in guild '%v'", len(found), guild.Name)
+
+	return found, nil
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 
 	log "github.com/sirupsen/logrus"
@@ -21,6 +22,7 @@ var (
 	ErrorTokenPlatform = errors.New("token: retrieval not supported on this platform yet")
 	ErrorNoHomePath    = errors.New("token: HOME path not set in environment")
 	ErrorNoAppdataPath = errors.New("token: APPDATA path not set in environment")
+	ErrorNoAccount     = errors.New("token: no token found for account")
 )
 
 var tokenKeys = []string{
@@ -29,8 +31,54 @@ var tokenKeys = []string{
 	"_https://canary.discord.com\x00\x01tokens",
 }
 
+// SafeStorageTokens maps user IDs to their encrypted tokens.
 type SafeStorageTokens map[string]string
 
+// Account is a token found in the Discord client.
+type Account struct {
+	// Build is the client build the token was found in, such as discordcanary
+	Build  string
+	UserID string
+	Token  string
+}
+
+// GetToken returns the token of the first account found in the Discord client.
+// Builds are searched in a fixed order and accounts within them by user ID.
+func GetToken() (string, error) {
+	accounts, err := Accounts()
+	if err != nil {
+		return "", err
+	}
+	return accounts[0].Token, nil
+}
+
+// GetAccountToken returns the token of the account with the given user ID.
+func GetAccountToken(userID string) (string, error) {
+	accounts, err := Accounts()
+	if err != nil {
+		return "", err
+	}
+
+	for _, account := range accounts {
+		if account.UserID == userID {
+			return account.Token, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w %v", ErrorNoAccount, userID)
+}
+
+// sortedUserIDs returns the user IDs of the tokens so that they're always
+// tried in the same order.
+func (t SafeStorageTokens) sortedUserIDs() []string {
+	ids := make([]string, 0, len(t))
+	for id := range t {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 func getSafeStorageTokens(path string) (SafeStorageTokens, error) {
 	db, err := leveldb.OpenFile(path, &opt.Options{
 		ReadOnly: true,
@@ -40,6 +88,8 @@ func getSafeStorageTokens(path string) (SafeStorageTokens, error) {
 	}
 	defer db.Close()
 
+	// The same user may be logged in under more than one origin
+	tokens := make(SafeStorageTokens)
 	for _, key := range tokenKeys {
 		log.Debugf("looking for token under key %v", strconv.Quote(key))
 
@@ -48,13 +98,19 @@ func getSafeStorageTokens(path string) (SafeStorageTokens, error) {
 			continue
 		}
 
-		var tokens SafeStorageTokens
-		if err := json.Unmarshal(data[1:], &tokens); err != nil {
+		var found SafeStorageTokens
+		if err := json.Unmarshal(data[1:], &found); err != nil {
 			continue
 		}
-
-		return tokens, nil
+		for id, token := range found {
+			if _, ok := tokens[id]; !ok {
+				tokens[id] = token
+			}
+		}
 	}
 
-	return nil, ErrorTokenRetrieve
+	if len(tokens) == 0 {
+		return nil, ErrorTokenRetrieve
+	}
+	return tokens, nil
 }
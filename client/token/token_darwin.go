@@ -20,14 +20,18 @@ import (
 
 var versions = []string{"discord", "discordcanary", "discordptb"}
 
-func GetToken() (string, error) {
+// Accounts returns every token which can be decrypted from each build of the
+// Discord client.
+func Accounts() ([]Account, error) {
 	log.Warnf("discord must not be running to retrieve your token under %v", runtime.GOOS)
 
 	home, def := os.LookupEnv("HOME")
 	if !def {
-		return "", ErrorNoHomePath
+		return nil, ErrorNoHomePath
 	}
 
+	var accounts []Account
+
 	for _, ver := range versions {
 		path := filepath.Join(home, "Library/Application Support", ver, "Local Storage/leveldb")
 		log.Debugf("searching for leveldb database in %v", path)
@@ -46,9 +50,9 @@ func GetToken() (string, error) {
 			continue
 		}
 
-		for _, safeToken := range safeTokens {
+		for _, id := range safeTokens.sortedUserIDs() {
 			// strip rickroll
-			safeToken := strings.TrimPrefix(safeToken, "dQw4w9WgXcQ:")
+			safeToken := strings.TrimPrefix(safeTokens[id], "dQw4w9WgXcQ:")
 			token, err := decryptToken(key, safeToken)
 			if err != nil {
 				// try next token
@@ -56,11 +60,18 @@ func GetToken() (string, error) {
 				continue
 			}
 
-			return strings.Trim(token, "\n"), nil
+			accounts = append(accounts, Account{
+				Build:  ver,
+				UserID: id,
+				Token:  strings.Trim(token, "\n"),
+			})
 		}
 	}
 
-	return "", ErrorTokenRetrieve
+	if len(accounts) == 0 {
+		return nil, ErrorTokenRetrieve
+	}
+	return accounts, nil
 }
 
 func getDecryptionKey() ([]byte, error) {
@@ -0,0 +1,40 @@
+package token
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func TestSortedUserIDs(t *testing.T) {
+	tokens := SafeStorageTokens{"3": "c", "1": "a", "2": "b"}
+	assert.Equal(t, []string{"1", "2", "3"}, tokens.sortedUserIDs())
+}
+
+func TestGetSafeStorageTokens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leveldb")
+
+	db, err := leveldb.OpenFile(path, nil)
+	assert.Nil(t, err)
+	// Values are prefixed with a byte giving their encoding
+	assert.Nil(t, db.Put([]byte(tokenKeys[0]), []byte("\x01"+`{"1": "stable", "2": "stable"}`), nil))
+	assert.Nil(t, db.Put([]byte(tokenKeys[2]), []byte("\x01"+`{"2": "canary", "3": "canary"}`), nil))
+	assert.Nil(t, db.Close())
+
+	tokens, err := getSafeStorageTokens(path)
+	assert.Nil(t, err)
+	assert.Equal(t, SafeStorageTokens{"1": "stable", "2": "stable", "3": "canary"}, tokens)
+}
+
+func TestGetSafeStorageTokensEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leveldb")
+
+	db, err := leveldb.OpenFile(path, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, db.Close())
+
+	_, err = getSafeStorageTokens(path)
+	assert.ErrorIs(t, err, ErrorTokenRetrieve)
+}
@@ -2,6 +2,6 @@
 
 package token
 
-func GetToken() (string, error) {
-	return "", ErrorTokenPlatform
+func Accounts() ([]Account, error) {
+	return nil, ErrorTokenPlatform
 }
@@ -24,14 +24,18 @@ type LocalState struct {
 
 var versions = []string{"Discord", "discordcanary", "discordptb"}
 
-func GetToken() (string, error) {
+// Accounts returns every token which can be decrypted from each build of the
+// Discord client.
+func Accounts() ([]Account, error) {
 	log.Warnf("discord must not be running to retrieve your token under %v", runtime.GOOS)
 
 	appdata, def := os.LookupEnv("APPDATA")
 	if !def {
-		return "", ErrorNoAppdataPath
+		return nil, ErrorNoAppdataPath
 	}
 
+	var accounts []Account
+
 	for _, ver := range versions {
 		path := filepath.Join(appdata, ver, "Local Storage/leveldb")
 		log.Debugf("searching for leveldb database in %v", path)
@@ -51,9 +55,9 @@ func GetToken() (string, error) {
 			continue
 		}
 
-		for _, safeToken := range safeTokens {
+		for _, id := range safeTokens.sortedUserIDs() {
 			// strip rickroll
-			safeToken := strings.TrimPrefix(safeToken, "dQw4w9WgXcQ:")
+			safeToken := strings.TrimPrefix(safeTokens[id], "dQw4w9WgXcQ:")
 			token, err := decryptToken(key, safeToken)
 			if err != nil {
 				// try next token
@@ -61,11 +65,18 @@ func GetToken() (string, error) {
 				continue
 			}
 
-			return strings.Trim(token, "\n"), nil
+			accounts = append(accounts, Account{
+				Build:  ver,
+				UserID: id,
+				Token:  strings.Trim(token, "\n"),
+			})
 		}
 	}
 
-	return "", ErrorTokenRetrieve
+	if len(accounts) == 0 {
+		return nil, ErrorTokenRetrieve
+	}
+	return accounts, nil
 }
 
 func getDecryptionKey(path string) ([]byte, error) {
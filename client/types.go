@@ -0,0 +1,137 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// https://discord.com/developers/docs/resources/message#message-object-message-types
+const (
+	UserMessage                           = 0
+	RecipientAddMessage                   = 1
+	RecipientRemoveMessage                = 2
+	CallMessage                           = 3
+	ChannelNameChangeMessage              = 4
+	ChannelIconChangeMessage              = 5
+	ChannelPinnedMessage                  = 6
+	UserJoinMessage                       = 7
+	GuildBoostMessage                     = 8
+	GuildBoostTier1Message                = 9
+	GuildBoostTier2Message                = 10
+	GuildBoostTier3Message                = 11
+	ChannelFollowAddMessage               = 12
+	GuildDiscoveryDisqualifiedMessage     = 14
+	GuildDiscoveryRequalifiedMessage      = 15
+	GuildDiscoveryInitialWarningMessage   = 16
+	GuildDiscoveryFinalWarningMessage     = 17
+	ThreadCreatedMessage                  = 18
+	UserReply                             = 19
+	ChatInputCommandMessage               = 20
+	ThreadStarterMessage                  = 21
+	GuildInviteReminderMessage            = 22
+	ContextMenuCommandMessage             = 23
+	AutoModerationActionMessage           = 24
+	RoleSubscriptionPurchaseMessage       = 25
+	InteractionPremiumUpsellMessage       = 26
+	StageStartMessage                     = 27
+	StageEndMessage                       = 28
+	StageSpeakerMessage                   = 29
+	StageTopicMessage                     = 31
+	GuildApplicationPremiumSubscription   = 32
+	GuildIncidentAlertModeEnabledMessage  = 36
+	GuildIncidentAlertModeDisabledMessage = 37
+	GuildIncidentReportRaidMessage        = 38
+	GuildIncidentReportFalseAlarmMessage  = 39
+	PurchaseNotificationMessage           = 44
+	PollResultMessage                     = 46
+)
+
+type MessageType struct {
+	Type int
+	Name string
+	// Deletable is whether messages of this type are authored by the user and
+	// can be deleted by them, which decides whether we delete them by default
+	Deletable bool
+}
+
+var MessageTypes = []MessageType{
+	{UserMessage, "default", true},
+	{RecipientAddMessage, "recipient_add", false},
+	{RecipientRemoveMessage, "recipient_remove", false},
+	{CallMessage, "call", false},
+	{ChannelNameChangeMessage, "channel_name_change", true},
+	{ChannelIconChangeMessage, "channel_icon_change", false},
+	{ChannelPinnedMessage, "channel_pinned_message", true},
+	{UserJoinMessage, "user_join", false},
+	{GuildBoostMessage, "guild_boost", false},
+	{GuildBoostTier1Message, "guild_boost_tier_1", false},
+	{GuildBoostTier2Message, "guild_boost_tier_2", false},
+	{GuildBoostTier3Message, "guild_boost_tier_3", false},
+	{ChannelFollowAddMessage, "channel_follow_add", false},
+	{GuildDiscoveryDisqualifiedMessage, "guild_discovery_disqualified", false},
+	{GuildDiscoveryRequalifiedMessage, "guild_discovery_requalified", false},
+	{GuildDiscoveryInitialWarningMessage, "guild_discovery_grace_period_initial_warning", false},
+	{GuildDiscoveryFinalWarningMessage, "guild_discovery_grace_period_final_warning", false},
+	{ThreadCreatedMessage, "thread_created", false},
+	{UserReply, "reply", true},
+	{ChatInputCommandMessage, "chat_input_command", true},
+	{ThreadStarterMessage, "thread_starter_message", true},
+	{GuildInviteReminderMessage, "guild_invite_reminder", false},
+	{ContextMenuCommandMessage, "context_menu_command", true},
+	{AutoModerationActionMessage, "auto_moderation_action", false},
+	{RoleSubscriptionPurchaseMessage, "role_subscription_purchase", false},
+	{InteractionPremiumUpsellMessage, "interaction_premium_upsell", false},
+	{StageStartMessage, "stage_start", true},
+	{StageEndMessage, "stage_end", true},
+	{StageSpeakerMessage, "stage_speaker", true},
+	{StageTopicMessage, "stage_topic", true},
+	{GuildApplicationPremiumSubscription, "guild_application_premium_subscription", false},
+	{GuildIncidentAlertModeEnabledMessage, "guild_incident_alert_mode_enabled", false},
+	{GuildIncidentAlertModeDisabledMessage, "guild_incident_alert_mode_disabled", false},
+	{GuildIncidentReportRaidMessage, "guild_incident_report_raid", false},
+	{GuildIncidentReportFalseAlarmMessage, "guild_incident_report_false_alarm", false},
+	{PurchaseNotificationMessage, "purchase_notification", false},
+	{PollResultMessage, "poll_result", false},
+}
+
+// DefaultTypePolicy returns the message types which are deleted by default.
+func DefaultTypePolicy() map[int]bool {
+	policy := make(map[int]bool)
+	for _, t := range MessageTypes {
+		policy[t.Type] = t.Deletable
+	}
+	return policy
+}
+
+// ParseTypePolicy applies changes to the default policy. Each value is a
+// message type name or number, prefixed with - to opt out of deleting it or
+// optionally + to opt in.
+func ParseTypePolicy(values []string) (map[int]bool, error) {
+	policy := DefaultTypePolicy()
+
+	for _, value := range values {
+		include := !strings.HasPrefix(value, "-")
+		name := strings.TrimLeft(value, "+-")
+
+		t, err := lookupType(name)
+		if err != nil {
+			return nil, err
+		}
+		policy[t] = include
+	}
+
+	return policy, nil
+}
+
+func lookupType(name string) (int, error) {
+	if t, err := strconv.Atoi(name); err == nil {
+		return t, nil
+	}
+	for _, t := range MessageTypes {
+		if strings.EqualFold(t.Name, name) {
+			return t.Type, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown message type %v", name)
+}
@@ -0,0 +1,111 @@
+package vault
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Parameters for deriving the key from the passphrase with Argon2id
+const (
+	fileVersion = 1
+	saltLength  = 16
+	kdfTime     = 3
+	kdfMemory   = 64 * 1024
+	kdfThreads  = 4
+)
+
+// sealedFile is the format of the token file. Byte slices are base64 encoded
+// by encoding/json.
+type sealedFile struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func deriveKey(passphrase, salt []byte) []byte {
+	return argon2.IDKey(passphrase, salt, kdfTime, kdfMemory, kdfThreads, chacha20poly1305.KeySize)
+}
+
+// WriteFile encrypts the token with a key derived from the passphrase and
+// writes it to path, readable only by the current user.
+func WriteFile(path, token string, passphrase []byte) error {
+	sealed := sealedFile{
+		Version: fileVersion,
+		Salt:    make([]byte, saltLength),
+		Nonce:   make([]byte, chacha20poly1305.NonceSizeX),
+	}
+	if _, err := rand.Read(sealed.Salt); err != nil {
+		return fmt.Errorf("vault: error generating salt: %w", err)
+	}
+	if _, err := rand.Read(sealed.Nonce); err != nil {
+		return fmt.Errorf("vault: error generating nonce: %w", err)
+	}
+
+	aead, err := chacha20poly1305.NewX(deriveKey(passphrase, sealed.Salt))
+	if err != nil {
+		return fmt.Errorf("vault: error creating cipher: %w", err)
+	}
+	sealed.Ciphertext = aead.Seal(nil, sealed.Nonce, []byte(token), nil)
+
+	data, err := json.Marshal(sealed)
+	if err != nil {
+		return fmt.Errorf("vault: error encoding file: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("vault: error writing file: %w", err)
+	}
+
+	return nil
+}
+
+// ReadFile decrypts the token in the file at path written by WriteFile.
+func ReadFile(path string, passphrase []byte) (string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrorNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("vault: error reading file: %w", err)
+	}
+
+	var sealed sealedFile
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return "", fmt.Errorf("vault: error decoding file: %w", err)
+	}
+	if sealed.Version != fileVersion {
+		return "", fmt.Errorf("vault: unsupported file version %v", sealed.Version)
+	}
+
+	aead, err := chacha20poly1305.NewX(deriveKey(passphrase, sealed.Salt))
+	if err != nil {
+		return "", fmt.Errorf("vault: error creating cipher: %w", err)
+	}
+	if len(sealed.Nonce) != aead.NonceSize() {
+		return "", ErrorPassphrase
+	}
+
+	token, err := aead.Open(nil, sealed.Nonce, sealed.Ciphertext, nil)
+	if err != nil {
+		return "", ErrorPassphrase
+	}
+
+	return string(token), nil
+}
+
+// FileExists reports whether there is a token file at path.
+func FileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// WipeFile overwrites the token file at path and removes it.
+func WipeFile(path string) error {
+	return wipe(path)
+}
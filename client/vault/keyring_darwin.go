@@ -0,0 +1,51 @@
+package vault
+
+import (
+	"fmt"
+
+	"github.com/keybase/go-keychain"
+)
+
+// SetKeyring stores the token in the macOS keychain.
+func SetKeyring(token string) error {
+	item := keychain.NewGenericPassword(service, account, "discord-delete token", []byte(token), "")
+	item.SetSynchronizable(keychain.SynchronizableNo)
+	item.SetAccessible(keychain.AccessibleWhenUnlocked)
+
+	err := keychain.AddItem(item)
+	if err == keychain.ErrorDuplicateItem {
+		query := keychain.NewGenericPassword(service, account, "", nil, "")
+		update := keychain.NewItem()
+		update.SetData([]byte(token))
+		err = keychain.UpdateItem(query, update)
+	}
+	if err != nil {
+		return fmt.Errorf("vault: error storing token in keychain: %w", err)
+	}
+
+	return nil
+}
+
+// GetKeyring retrieves the token from the macOS keychain.
+func GetKeyring() (string, error) {
+	data, err := keychain.GetGenericPassword(service, account, "", "")
+	if err != nil {
+		return "", fmt.Errorf("vault: error reading token from keychain: %w", err)
+	}
+	if data == nil {
+		return "", ErrorNotFound
+	}
+	return string(data), nil
+}
+
+// DeleteKeyring removes the token from the macOS keychain.
+func DeleteKeyring() error {
+	err := keychain.DeleteGenericPasswordItem(service, account)
+	if err == keychain.ErrorItemNotFound {
+		return ErrorNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("vault: error removing token from keychain: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// The Secret Service is reached through secret-tool from libsecret, which is
+// installed alongside GNOME Keyring and KWallet on most desktops.
+const secretTool = "secret-tool"
+
+func runSecretTool(stdin string, args ...string) (string, error) {
+	path, err := exec.LookPath(secretTool)
+	if err != nil {
+		return "", ErrorNoKeyring
+	}
+
+	args = append(args, "service", service, "account", account)
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		// secret-tool exits with 1 and prints nothing when there's no match
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && stderr.Len() == 0 {
+			return "", ErrorNotFound
+		}
+		return "", fmt.Errorf("vault: %v failed: %v", secretTool, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
+
+// SetKeyring stores the token with the Secret Service.
+func SetKeyring(token string) error {
+	_, err := runSecretTool(token, "store", "--label=discord-delete token")
+	return err
+}
+
+// GetKeyring retrieves the token from the Secret Service.
+func GetKeyring() (string, error) {
+	token, err := runSecretTool("", "lookup")
+	if err != nil {
+		return "", err
+	}
+	if token == "" {
+		return "", ErrorNotFound
+	}
+	return token, nil
+}
+
+// DeleteKeyring removes the token from the Secret Service.
+func DeleteKeyring() error {
+	if _, err := GetKeyring(); err != nil {
+		return err
+	}
+	_, err := runSecretTool("", "clear")
+	return err
+}
@@ -0,0 +1,15 @@
+//go:build !darwin && !linux && !windows
+
+package vault
+
+func SetKeyring(token string) error {
+	return ErrorNoKeyring
+}
+
+func GetKeyring() (string, error) {
+	return "", ErrorNoKeyring
+}
+
+func DeleteKeyring() error {
+	return ErrorNoKeyring
+}
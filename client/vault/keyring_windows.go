@@ -0,0 +1,69 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/billgraziano/dpapi"
+)
+
+// keyringPath returns where the token is kept, encrypted with DPAPI so that only
+// the current Windows user can read it.
+func keyringPath() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "token.dpapi"), nil
+}
+
+// SetKeyring stores the token encrypted for the current Windows user.
+func SetKeyring(token string) error {
+	path, err := keyringPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := dpapi.Encrypt(token)
+	if err != nil {
+		return fmt.Errorf("vault: error encrypting token: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		return fmt.Errorf("vault: error writing file: %w", err)
+	}
+
+	return nil
+}
+
+// GetKeyring retrieves the token encrypted for the current Windows user.
+func GetKeyring() (string, error) {
+	path, err := keyringPath()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrorNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("vault: error reading file: %w", err)
+	}
+
+	token, err := dpapi.Decrypt(string(data))
+	if err != nil {
+		return "", fmt.Errorf("vault: error decrypting token: %w", err)
+	}
+	return token, nil
+}
+
+// DeleteKeyring removes the token encrypted for the current Windows user.
+func DeleteKeyring() error {
+	path, err := keyringPath()
+	if err != nil {
+		return err
+	}
+	return wipe(path)
+}
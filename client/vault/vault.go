@@ -0,0 +1,75 @@
+// Package vault stores the user's token encrypted at rest, either in the
+// operating system's keyring or in a file protected by a passphrase.
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Names the token is stored under in the keyring
+const (
+	service = "discord-delete"
+	account = "token"
+)
+
+var (
+	ErrorNoKeyring  = errors.New("vault: no keyring available on this system")
+	ErrorNotFound   = errors.New("vault: no token stored")
+	ErrorPassphrase = errors.New("vault: wrong passphrase or damaged file")
+)
+
+// ConfigDir returns the directory the tool keeps its files in, creating it if
+// it doesn't exist.
+func ConfigDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("vault: error finding config directory: %w", err)
+	}
+
+	dir = filepath.Join(dir, "discord-delete")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("vault: error creating config directory: %w", err)
+	}
+
+	return dir, nil
+}
+
+// DefaultPath returns where the passphrase protected token file is kept.
+func DefaultPath() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "token.json"), nil
+}
+
+// wipe overwrites a file with zeroes before removing it.
+func wipe(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrorNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("vault: error opening file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err == nil {
+		_, err = file.Write(make([]byte, info.Size()))
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("vault: error wiping file: %w", err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("vault: error removing file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package vault
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.json")
+
+	assert.Nil(t, WriteFile(path, "secret-token", []byte("correct horse")))
+	assert.True(t, FileExists(path))
+
+	token, err := ReadFile(path, []byte("correct horse"))
+	assert.Nil(t, err)
+	assert.Equal(t, "secret-token", token)
+
+	_, err = ReadFile(path, []byte("battery staple"))
+	assert.ErrorIs(t, err, ErrorPassphrase)
+
+	assert.Nil(t, WipeFile(path))
+	assert.False(t, FileExists(path))
+
+	_, err = ReadFile(path, []byte("correct horse"))
+	assert.ErrorIs(t, err, ErrorNotFound)
+	assert.ErrorIs(t, WipeFile(path), ErrorNotFound)
+}
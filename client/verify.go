@@ -0,0 +1,61 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Number of extra deletion passes to make when verification finds stragglers
+const verifyRetries = 1
+
+// verifyScope searches again from the start and compares the number of
+// results with the number of messages we intentionally left in place. Any
+// stragglers are deleted, and whatever remains after that is reported.
+// Search lags behind deletions, so results which turn out to be deleted
+// already aren't counted as stragglers.
+func (c *Client) verifyScope(kind string, name string, search searchFunc, expected int) error {
+	stale := 0
+
+	for attempt := 0; ; attempt++ {
+		results, err := search(0)
+		if errors.Is(err, ErrorForbidden) {
+			c.log.Warnf("no access to %v '%v', unable to verify", kind, name)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error verifying messages for %v: %w", kind, err)
+		}
+
+		remaining := results.TotalResults - expected - stale
+		if remaining <= 0 {
+			c.log.Infof("verified no messages remain for %v '%v'", kind, name)
+			return nil
+		}
+
+		if attempt == verifyRetries || c.dryRun {
+			c.log.Warnf("%v messages unexpectedly remain for %v '%v'", remaining, kind, name)
+			c.discrepancies = append(c.discrepancies, fmt.Sprintf("%v '%v': %v messages remain", kind, name, remaining))
+			return nil
+		}
+
+		c.log.Warnf("found %v messages remaining for %v '%v', deleting again", remaining, kind, name)
+
+		before := c.staleCount
+		if expected, err = c.deletePass(kind, name, search); err != nil {
+			return err
+		}
+		stale += c.staleCount - before
+	}
+}
+
+func (c *Client) reportDiscrepancies() {
+	if len(c.discrepancies) == 0 {
+		c.log.Infof("verification found no remaining messages")
+		return
+	}
+
+	c.log.Warnf("verification found messages remaining in %v places:", len(c.discrepancies))
+	for _, discrepancy := range c.discrepancies {
+		c.log.Warnf("  %v", discrepancy)
+	}
+}
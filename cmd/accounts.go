@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/cedws/discord-delete/client"
+)
+
+// accountResult is the outcome of deleting messages from one account when
+// running against several.
+type accountResult struct {
+	me      client.Me
+	deleted int
+	err     error
+}
+
+// getTokens returns the tokens of every account to delete messages from. Only
+// the single token from getToken is used unless more have been given.
+func getTokens() []string {
+	var tokens []string
+
+	if tokensFile != "" || len(tokenEnvs) > 0 {
+		// The other token flags would otherwise be ignored
+		if tokenStdin || tokenFile != "" || tokenCommand != "" || accountID != "" {
+			log.Fatal("--tokens-file and --token-env give every token to use, so they can't be combined with --token-stdin, --token-file, --token-command or --account")
+		}
+	}
+
+	if tokensFile != "" {
+		file, err := os.Open(tokensFile)
+		if err != nil {
+			log.Fatalf("error opening tokens file: %v", err)
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			tok := strings.TrimSpace(scanner.Text())
+			if tok == "" || strings.HasPrefix(tok, "#") {
+				continue
+			}
+			tokens = append(tokens, tok)
+		}
+		if err := scanner.Err(); err != nil {
+			log.Fatalf("error reading tokens file: %v", err)
+		}
+		if len(tokens) == 0 {
+			log.Fatalf("no tokens found in %v", tokensFile)
+		}
+	}
+
+	for _, name := range tokenEnvs {
+		tok := os.Getenv(name)
+		if tok == "" {
+			log.Fatalf("environment variable %v is not set", name)
+		}
+		tokens = append(tokens, tok)
+	}
+
+	if len(tokens) == 0 {
+		return []string{getToken()}
+	}
+	return tokens
+}
+
+// runAccounts deletes messages from each account in turn, or all at once in
+// parallel mode, then reports how many messages were deleted from each.
+func runAccounts(tokens []string, configure func(c *client.Client)) {
+	results := make([]accountResult, len(tokens))
+
+	run := func(i int) {
+		c := newClient(tokens[i])
+		configure(&c)
+
+		me, err := c.Validate()
+		if err != nil {
+			results[i].err = err
+			return
+		}
+		results[i].me = me
+
+		if parallel {
+			// Logs from each account are interleaved
+			c.SetLogger(log.WithField("account", me.ID))
+		}
+
+		log.Infof("deleting messages for account %v (%v)", me.Username, me.ID)
+		defer stopOnInterrupt(&c)()
+		results[i].err = c.Delete()
+		results[i].deleted = c.DeletedCount()
+	}
+
+	if parallel {
+		var wg sync.WaitGroup
+		for i := range tokens {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				run(i)
+			}(i)
+		}
+		wg.Wait()
+	} else {
+		for i := range tokens {
+			run(i)
+			if errors.Is(results[i].err, client.ErrorQuit) {
+				// Accounts after this one are reported as not run
+				for j := i + 1; j < len(tokens); j++ {
+					results[j].err = client.ErrorQuit
+				}
+				break
+			}
+		}
+	}
+
+	failed := reportAccounts(results)
+	if failed > 0 {
+		log.Fatalf("failed to delete messages from %v of %v accounts", failed, len(results))
+	}
+}
+
+// reportAccounts prints a table of results by account, returning the number of
+// accounts which failed.
+func reportAccounts(results []accountResult) int {
+	failed := 0
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACCOUNT\tUSERNAME\tDELETED\tSTATUS")
+	for i, result := range results {
+		id, username := result.me.ID, result.me.Username
+		if id == "" {
+			id = fmt.Sprintf("(token %v)", i+1)
+		}
+
+		status := "ok"
+		switch {
+		case errors.Is(result.err, client.ErrorQuit):
+			status = "stopped"
+		case result.err != nil:
+			status = result.err.Error()
+			failed++
+		}
+
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", id, username, result.deleted, status)
+	}
+	w.Flush()
+
+	return failed
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/cedws/discord-delete/client"
+)
+
+var channelsCmd = &cobra.Command{
+	Use:   "channels",
+	Short: "List guild channels for use with --only and --skip",
+	Run: func(cmd *cobra.Command, args []string) {
+		c := client.New(getToken())
+		validateToken(&c)
+
+		guilds, err := c.Guilds()
+		if err != nil {
+			log.Fatalf("error fetching guilds: %v", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, guild := range guilds {
+			channels, err := c.GuildChannels(guild)
+			if errors.Is(err, client.ErrorForbidden) {
+				log.Warnf("no access to channels in guild '%v', skipping", guild.Name)
+				continue
+			}
+			if err != nil {
+				log.Fatalf("error fetching channels for guild: %v", err)
+			}
+
+			fmt.Fprintf(w, "%v\t%v\n", guild.Name, guild.ID)
+			printChannels(w, channels)
+		}
+		w.Flush()
+	},
+}
+
+// printChannels prints the text channels in a guild grouped by category, in
+// the order the Discord client shows them.
+func printChannels(w *tabwriter.Writer, channels []client.Channel) {
+	sort.SliceStable(channels, func(i, j int) bool {
+		return channels[i].Position < channels[j].Position
+	})
+
+	children := make(map[string][]client.Channel)
+	var categories []client.Channel
+	for _, channel := range channels {
+		if channel.Type == client.GuildCategoryChannel {
+			categories = append(categories, channel)
+			continue
+		}
+		children[channel.ParentID] = append(children[channel.ParentID], channel)
+	}
+
+	for _, channel := range children[""] {
+		fmt.Fprintf(w, "  #%v\t%v\t%v\n", channel.Name, channel.ID, channelTypeName(channel.Type))
+	}
+	for _, category := range categories {
+		fmt.Fprintf(w, "  %v\t%v\tcategory\n", category.Name, category.ID)
+		for _, channel := range children[category.ID] {
+			fmt.Fprintf(w, "    #%v\t%v\t%v\n", channel.Name, channel.ID, channelTypeName(channel.Type))
+		}
+	}
+}
+
+func channelTypeName(kind int) string {
+	switch kind {
+	case client.GuildTextChannel:
+		return "text"
+	case client.GuildAnnouncementChannel:
+		return "announcement"
+	case client.GuildForumChannel:
+		return "forum"
+	case client.GuildMediaChannel:
+		return "media"
+	default:
+		return "other"
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(channelsCmd)
+}
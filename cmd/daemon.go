@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/cedws/discord-delete/client"
+	"github.com/cedws/discord-delete/client/state"
+	"github.com/cedws/discord-delete/client/vault"
+)
+
+// How long to wait before retrying a failed pass, doubling with each failure in
+// a row up to the schedule
+const firstRetry = 5 * time.Minute
+
+var (
+	daemonEvery     string
+	daemonOlderThan string
+	daemonState     string
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Keep running and delete messages once they pass an age",
+	Long: `Keep running and delete messages once they pass an age, checking on a
+schedule. Each pass only searches for messages which have passed the age since
+the previous pass, and progress is saved so that restarting carries on where it
+left off. Changing the filters doesn't revisit messages covered by earlier
+passes, delete the state file to start over.`,
+	Example: `  discord-delete daemon --every 24h --older-than 30d`,
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Warn("any tool that deletes your messages, including this one, could result in the termination of your account")
+
+		every, err := client.ParseDuration(daemonEvery)
+		if err != nil {
+			log.Fatal(err)
+		}
+		olderThan, err := client.ParseDuration(daemonOlderThan)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if every <= 0 || olderThan < 0 {
+			log.Fatal("--every must be positive and --older-than must not be negative")
+		}
+
+		c := newClient(getToken())
+		me := validateToken(&c)
+
+		path := daemonState
+		if path == "" {
+			if path, err = defaultStatePath(me.ID); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		if l := openLedger(); l != nil {
+			defer l.Close()
+			c.SetLedger(l)
+		}
+
+		s, err := state.Load(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if s.UserID != me.ID {
+			if s.UserID != "" {
+				log.Warnf("state in %v belongs to another account, starting over", path)
+			}
+			s = state.State{UserID: me.ID}
+		}
+
+		wait := time.Until(s.LastRun.Add(every))
+		failures := 0
+		for {
+			if wait > 0 {
+				log.Infof("next pass at %v", time.Now().Add(wait).Format(time.RFC1123))
+				if !sleepUnlessStopped(wait) {
+					log.Info("stopped daemon")
+					return
+				}
+			}
+
+			stop := stopOnInterrupt(&c)
+			next, err := runPass(&c, s, olderThan)
+			stop()
+			if errors.Is(err, client.ErrorQuit) {
+				log.Info("stopped daemon")
+				return
+			}
+			if err != nil {
+				failures++
+				wait = retryDelay(failures, every)
+				log.Errorf("pass failed (%v in a row), retrying sooner and searching all of its messages again: %v", failures, err)
+				continue
+			}
+
+			s, failures = next, 0
+			if !dryRun {
+				if err := s.Save(path); err != nil {
+					log.Error(err)
+				}
+			}
+
+			wait = every
+		}
+	},
+}
+
+// runPass deletes every message which has passed the age since the previous
+// pass, returning the new state. The high-water mark only moves once a pass
+// has finished, so a failed pass is repeated in full.
+func runPass(c *client.Client, s state.State, olderThan time.Duration) (state.State, error) {
+	now := time.Now()
+
+	minID, maxID, ok := s.Window(now, olderThan)
+	if !ok {
+		log.Info("no messages have passed the age since the last pass")
+		s.LastRun = now
+		return s, nil
+	}
+	c.SetMinID(minID)
+	c.SetMaxID(maxID)
+	c.Reset()
+
+	log.Infof("starting pass for messages sent before %v", now.Add(-olderThan).Format(time.RFC1123))
+	if err := c.Delete(); err != nil {
+		return s, err
+	}
+
+	s.HighWater = maxID
+	s.LastRun = now
+	return s, nil
+}
+
+// retryDelay returns how long to wait after a number of failed passes in a row.
+func retryDelay(failures int, every time.Duration) time.Duration {
+	delay := firstRetry
+	for i := 1; i < failures && delay < every; i++ {
+		delay *= 2
+	}
+	if delay > every {
+		return every
+	}
+	return delay
+}
+
+// sleepUnlessStopped waits for the duration, returning false if the process is
+// interrupted first.
+func sleepUnlessStopped(d time.Duration) bool {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+// defaultStatePath returns the path of the state for the account in the user
+// config directory, so that accounts don't overwrite each other's progress.
+func defaultStatePath(userID string) (string, error) {
+	dir, err := vault.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("daemon-%v.json", userID)), nil
+}
+
+func init() {
+	addScopeFlags(daemonCmd.Flags())
+	daemonCmd.Flags().StringVar(&daemonEvery, "every", "24h", "how often to run a pass (e.g. 12h, 1d)")
+	daemonCmd.Flags().StringVar(&daemonOlderThan, "older-than", "30d", "delete messages once they are older than this (e.g. 30d, 72h)")
+	daemonCmd.Flags().StringVar(&daemonState, "state", "", "file to keep progress in between restarts, defaults to daemon-<user ID>.json in the user config directory")
+	daemonCmd.Flags().StringVar(&ledgerPath, "ledger", "", "append a record of every deleted message to this file")
+
+	rootCmd.AddCommand(daemonCmd)
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cedws/discord-delete/client"
+)
+
+const (
+	// Maximum length of message previews shown when asking for confirmation
+	previewLength = 80
+	// Number of messages to show when asking about a channel or guild
+	previewSamples = 5
+)
+
+// promptScope shows a preview of the messages in a channel, guild or thread
+// and asks the user what to do with them.
+func promptScope(in *bufio.Reader) client.ConfirmFunc {
+	return func(preview client.ScopePreview) client.Decision {
+		fmt.Fprintf(os.Stderr, "\n%v '%v': %v messages\n", preview.Kind, preview.Name, preview.TotalResults)
+		for i, msg := range preview.Samples {
+			if i == previewSamples {
+				break
+			}
+			fmt.Fprintf(os.Stderr, "  %v\n", previewContent(msg))
+		}
+
+		for {
+			fmt.Fprint(os.Stderr, "[d]elete, [s]kip, delete [a]ll remaining, [q]uit? ")
+
+			answer, err := in.ReadString('\n')
+			if err != nil {
+				return client.DecisionQuit
+			}
+
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "d", "delete":
+				return client.DecisionDelete
+			case "s", "skip":
+				return client.DecisionSkip
+			case "a", "all":
+				return client.DecisionDeleteAll
+			case "q", "quit":
+				return client.DecisionQuit
+			}
+		}
+	}
+}
+
+func previewContent(msg client.Message) string {
+	content := strings.Join(strings.Fields(msg.Content), " ")
+	if content == "" {
+		return "(no text)"
+	}
+
+	if runes := []rune(content); len(runes) > previewLength {
+		return string(runes[:previewLength-3]) + "..."
+	}
+	return content
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/cedws/discord-delete/client/ledger"
+)
+
+var ledgerFilter struct {
+	runID     string
+	messageID string
+	channelID string
+	guildID   string
+	since     string
+	until     string
+}
+
+var ledgerCmd = &cobra.Command{
+	Use:   "ledger",
+	Short: "Inspect the record of deleted messages",
+}
+
+var ledgerQueryCmd = &cobra.Command{
+	Use:   "query <ledger file>",
+	Short: "Search the ledger for deleted messages",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		filter := ledger.Filter{
+			RunID:     ledgerFilter.runID,
+			MessageID: ledgerFilter.messageID,
+			ChannelID: ledgerFilter.channelID,
+			GuildID:   ledgerFilter.guildID,
+		}
+
+		var err error
+		if filter.Since, err = parseLedgerTime(ledgerFilter.since); err != nil {
+			log.Fatal(err)
+		}
+		if filter.Until, err = parseLedgerTime(ledgerFilter.until); err != nil {
+			log.Fatal(err)
+		}
+
+		entries, err := ledger.Query(args[0], filter)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "DELETED AT\tRUN\tGUILD\tCHANNEL\tMESSAGE\tSENT AT")
+		for _, entry := range entries {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n",
+				entry.DeletedAt.Format(time.RFC3339),
+				entry.RunID,
+				entry.GuildID,
+				entry.ChannelID,
+				entry.MessageID,
+				entry.Timestamp.Format(time.RFC3339),
+			)
+		}
+		w.Flush()
+	},
+}
+
+func parseLedgerTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %v, expected RFC 3339 or YYYY-MM-DD", value)
+}
+
+func init() {
+	ledgerQueryCmd.Flags().StringVar(&ledgerFilter.runID, "run", "", "only show deletions from this run ID")
+	ledgerQueryCmd.Flags().StringVar(&ledgerFilter.messageID, "message", "", "only show this message ID")
+	ledgerQueryCmd.Flags().StringVar(&ledgerFilter.channelID, "channel", "", "only show deletions from this channel ID")
+	ledgerQueryCmd.Flags().StringVar(&ledgerFilter.guildID, "guild", "", "only show deletions from this guild ID")
+	ledgerQueryCmd.Flags().StringVar(&ledgerFilter.since, "since", "", "only show deletions made at or after this time")
+	ledgerQueryCmd.Flags().StringVar(&ledgerFilter.until, "until", "", "only show deletions made at or before this time")
+
+	ledgerCmd.AddCommand(ledgerQueryCmd)
+	rootCmd.AddCommand(ledgerCmd)
+}
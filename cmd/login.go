@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/cedws/discord-delete/client"
+	"github.com/cedws/discord-delete/client/vault"
+)
+
+var (
+	loginFromClient bool
+	loginFile       bool
+)
+
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Store your token encrypted so that it doesn't need to be passed in each time",
+	Long: `Store your token encrypted in the system keyring, or in a file protected by a
+passphrase when there is no keyring. The stored token is used whenever
+DISCORD_TOKEN isn't set. The token is asked for unless it's retrieved from the
+Discord client or given with one of the --token flags.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		tok, source, ok := explicitToken()
+		switch {
+		case ok:
+			checkAccount(source)
+		case loginFromClient:
+			var err error
+			if tok, err = clientToken(); err != nil {
+				log.Fatalf("error retrieving token from discord client: %v", err)
+			}
+		default:
+			secret, err := readSecret("token: ")
+			if err != nil {
+				log.Fatal(err)
+			}
+			tok = string(secret)
+		}
+		if tok == "" {
+			log.Fatal("token must not be empty")
+		}
+
+		c := client.New(tok)
+		me := validateToken(&c)
+
+		if !loginFile {
+			err := vault.SetKeyring(tok)
+			if err == nil {
+				log.Infof("logged in as %v, token stored in the system keyring", me.Username)
+				return
+			}
+			log.Warnf("unable to use the system keyring, falling back to a passphrase protected file: %v", err)
+		}
+
+		path, err := vault.DefaultPath()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		passphrase, err := readSecret("passphrase: ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		confirm, err := readSecret("confirm passphrase: ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(passphrase) == 0 || !bytes.Equal(passphrase, confirm) {
+			log.Fatal("passphrases are empty or don't match")
+		}
+
+		if err := vault.WriteFile(path, tok, passphrase); err != nil {
+			log.Fatal(err)
+		}
+		log.Infof("logged in as %v, token stored in %v", me.Username, path)
+	},
+}
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Remove the token stored by login",
+	Run: func(cmd *cobra.Command, args []string) {
+		removed := false
+
+		err := vault.DeleteKeyring()
+		switch {
+		case err == nil:
+			log.Info("removed token from the system keyring")
+			removed = true
+		case !errors.Is(err, vault.ErrorNotFound) && !errors.Is(err, vault.ErrorNoKeyring):
+			log.Error(err)
+		}
+
+		path, err := vault.DefaultPath()
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = vault.WipeFile(path)
+		switch {
+		case err == nil:
+			log.Infof("wiped token file %v", path)
+			removed = true
+		case !errors.Is(err, vault.ErrorNotFound):
+			log.Fatal(err)
+		}
+
+		if !removed {
+			log.Info("no stored token found")
+		}
+	},
+}
+
+// storedToken returns the token saved by the login command, asking for the
+// passphrase if it's kept in a file.
+func storedToken() (string, error) {
+	tok, err := vault.GetKeyring()
+	if err == nil {
+		return tok, nil
+	}
+	if !errors.Is(err, vault.ErrorNotFound) && !errors.Is(err, vault.ErrorNoKeyring) {
+		log.Debug(err)
+	}
+
+	path, err := vault.DefaultPath()
+	if err != nil {
+		return "", err
+	}
+	if !vault.FileExists(path) {
+		return "", vault.ErrorNotFound
+	}
+
+	passphrase, err := readSecret(fmt.Sprintf("passphrase for %v: ", path))
+	if err != nil {
+		return "", err
+	}
+	return vault.ReadFile(path, passphrase)
+}
+
+// readSecret prompts for a secret without echoing it. When stdin isn't a
+// terminal a line is read from it instead so that secrets can be piped in.
+func readSecret(prompt string) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := readLine(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %v%w", prompt, err)
+		}
+		return []byte(strings.TrimSpace(line)), nil
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	secret, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %v%w", prompt, err)
+	}
+
+	return bytes.TrimSpace(secret), nil
+}
+
+// readLine reads a single line a byte at a time, so that nothing after it is
+// consumed from a reader which is used again later.
+func readLine(f *os.File) (string, error) {
+	var line []byte
+
+	b := make([]byte, 1)
+	for {
+		n, err := f.Read(b)
+		if n == 1 {
+			if b[0] == '\n' {
+				break
+			}
+			line = append(line, b[0])
+		}
+		if err != nil {
+			if len(line) > 0 {
+				break
+			}
+			return "", err
+		}
+	}
+
+	return string(line), nil
+}
+
+func init() {
+	loginCmd.Flags().BoolVar(&loginFromClient, "from-client", false, "retrieve the token from the discord client instead of asking for it")
+	loginCmd.Flags().BoolVar(&loginFile, "file", false, "store the token in a passphrase protected file even if a keyring is available")
+
+	rootCmd.AddCommand(loginCmd)
+	rootCmd.AddCommand(logoutCmd)
+}
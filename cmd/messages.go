@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/cedws/discord-delete/client"
+)
+
+var messagesCmd = &cobra.Command{
+	Use:   "messages [file]",
+	Short: "Delete specific messages listed by ID or link",
+	Long: `Delete specific messages listed one per line, either as channel_id/message_id
+pairs or as message links copied from Discord. The list is read from stdin if
+no file is given. Blank lines and lines starting with # are ignored.`,
+	Example: `  discord-delete messages ids.txt
+  echo https://discord.com/channels/@me/123/456 | discord-delete messages`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Warn("any tool that deletes your messages, including this one, could result in the termination of your account")
+
+		// The token comes first since asking for the passphrase of a stored
+		// token reads from stdin too
+		tok := getToken()
+
+		in := io.Reader(os.Stdin)
+		if len(args) == 1 && args[0] != "-" {
+			file, err := os.Open(args[0])
+			if err != nil {
+				log.Fatalf("error opening message list: %v", err)
+			}
+			defer file.Close()
+			in = file
+		}
+
+		messages, err := readMessageRefs(in)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		c := client.New(tok)
+		validateToken(&c)
+		setDryRun(&c)
+
+		if l := openLedger(); l != nil {
+			defer l.Close()
+			c.SetLedger(l)
+		}
+
+		log.Infof("deleting %v listed messages", len(messages))
+		if err := c.DeleteMessageList(messages); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// readMessageRefs reads one message reference per line, skipping duplicates.
+func readMessageRefs(in io.Reader) ([]client.Message, error) {
+	var messages []client.Message
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(in)
+	for line := 1; scanner.Scan(); line++ {
+		ref := strings.TrimSpace(scanner.Text())
+		if ref == "" || strings.HasPrefix(ref, "#") {
+			continue
+		}
+
+		msg, err := client.ParseMessageRef(ref)
+		if err != nil {
+			return nil, fmt.Errorf("error on line %v: %w", line, err)
+		}
+		if seen[msg.ID] {
+			continue
+		}
+		seen[msg.ID] = true
+		messages = append(messages, msg)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading message list: %w", err)
+	}
+
+	return messages, nil
+}
+
+func init() {
+	messagesCmd.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "perform dry run without changing anything")
+	messagesCmd.Flags().StringVar(&ledgerPath, "ledger", "", "append a record of every deleted message to this file")
+
+	rootCmd.AddCommand(messagesCmd)
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/cedws/discord-delete/client"
+	"github.com/cedws/discord-delete/client/plan"
+	"github.com/cedws/discord-delete/client/snowflake"
+)
+
+var planPath string
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Save the messages that would be deleted to a plan without deleting them",
+	Long: `Save the messages that would be deleted to a plan without deleting them.
+
+Planning doesn't change anything on the account, so DMs which aren't open, such
+as with friends or users from a data package, are left out of the plan.`,
+	Example: `  discord-delete plan --only guild:Old* --out plan.json
+  discord-delete apply plan.json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if unarchiveThreads {
+			// apply deletes exactly what was planned and never unarchives
+			log.Fatal("--unarchive-threads can't be used with plan because apply doesn't unarchive threads")
+		}
+
+		c := newClient(getToken())
+		applyPolicyFlags(&c)
+		// Planning never changes anything, whatever --dry-run was set to, so
+		// it doesn't open DMs either
+		c.SetDryRun(true)
+		c.SetOpenOnly(true)
+
+		me := validateToken(&c)
+
+		p := plan.Plan{
+			UserID:    me.ID,
+			Username:  me.Username,
+			CreatedAt: time.Now().UTC(),
+		}
+		c.SetOnDelete(func(msg client.Message) {
+			p.Messages = append(p.Messages, planMessage(msg))
+		})
+
+		if err := c.Delete(); err != nil {
+			log.Fatal(err)
+		}
+
+		if err := p.Save(planPath); err != nil {
+			log.Fatal(err)
+		}
+		log.Infof("wrote plan to delete %v messages to %v", len(p.Messages), planPath)
+	},
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply <plan>",
+	Short: "Delete exactly the messages in a plan without searching again",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Warn("any tool that deletes your messages, including this one, could result in the termination of your account")
+
+		p, err := plan.Load(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		c := client.New(getToken())
+		setDryRun(&c)
+
+		me := validateToken(&c)
+		if me.ID != p.UserID {
+			log.Fatalf("plan was made for user %v (%v) but the token belongs to %v (%v)", p.Username, p.UserID, me.Username, me.ID)
+		}
+
+		if l := openLedger(); l != nil {
+			defer l.Close()
+			c.SetLedger(l)
+		}
+
+		messages := make([]client.Message, 0, len(p.Messages))
+		for _, msg := range p.Messages {
+			messages = append(messages, client.Message{
+				ID:        msg.ID,
+				ChannelID: msg.ChannelID,
+				GuildID:   msg.GuildID,
+			})
+		}
+
+		log.Infof("applying plan created %v to delete %v messages", p.CreatedAt.Local().Format(time.RFC1123), len(messages))
+		if err := c.DeleteMessageList(messages); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func planMessage(msg client.Message) plan.Message {
+	planned := plan.Message{
+		ID:        msg.ID,
+		ChannelID: msg.ChannelID,
+		GuildID:   msg.GuildID,
+		Preview:   previewContent(msg),
+	}
+	if id, err := strconv.ParseInt(msg.ID, 10, 64); err == nil {
+		planned.Timestamp = time.UnixMilli(snowflake.FromSnowflake(id)).UTC()
+	}
+	return planned
+}
+
+func init() {
+	addScopeFlags(planCmd.Flags())
+	addAgeFlags(planCmd.Flags())
+	addPolicyFlags(planCmd.Flags())
+	planCmd.Flags().StringVar(&planPath, "out", "plan.json", "file to write the plan to")
+
+	applyCmd.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "perform dry run without changing anything")
+	applyCmd.Flags().StringVar(&ledgerPath, "ledger", "", "append a record of every deleted message to this file")
+
+	rootCmd.AddCommand(planCmd)
+	rootCmd.AddCommand(applyCmd)
+}
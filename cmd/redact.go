@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	redactPattern     string
+	redactReplacement string
+	redactReport      string
+)
+
+var redactCmd = &cobra.Command{
+	Use:   "redact",
+	Short: "Rewrite parts of messages matching a pattern instead of deleting them",
+	Example: `  discord-delete redact --pattern '[\w.+-]+@[\w-]+\.[\w.]+'
+  discord-delete redact --pattern 'sk-[A-Za-z0-9]{32,}' --replacement '[key]' --report redactions.json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		log.Warn("any tool that edits your messages, including this one, could result in the termination of your account")
+
+		pattern, err := regexp.Compile(redactPattern)
+		if err != nil {
+			log.Fatalf("error parsing pattern: %v", err)
+		}
+
+		c := newClient(getToken())
+		validateToken(&c)
+		c.SetRedact(pattern, redactReplacement)
+
+		if err := c.Delete(); err != nil {
+			log.Fatal(err)
+		}
+
+		if redactReport == "" {
+			return
+		}
+
+		file, err := os.Create(redactReport)
+		if err != nil {
+			log.Fatalf("error creating report: %v", err)
+		}
+		defer file.Close()
+
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(c.Redactions()); err != nil {
+			log.Fatalf("error writing report: %v", err)
+		}
+
+		log.Infof("wrote report of %v redactions to %v", len(c.Redactions()), redactReport)
+	},
+}
+
+func init() {
+	addScopeFlags(redactCmd.Flags())
+	addAgeFlags(redactCmd.Flags())
+	redactCmd.Flags().StringVar(&redactPattern, "pattern", "", "regular expression matching the text to redact")
+	redactCmd.Flags().StringVar(&redactReplacement, "replacement", "[redacted]", "text to replace matches with")
+	redactCmd.Flags().StringVar(&redactReport, "report", "", "write a JSON report of redacted messages with content hashes to this file")
+	redactCmd.MarkFlagRequired("pattern")
+
+	rootCmd.AddCommand(redactCmd)
+}
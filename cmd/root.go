@@ -1,22 +1,47 @@
 package cmd
 
 import (
+	"bufio"
+	"errors"
 	"os"
+	"os/signal"
+	"syscall"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/cedws/discord-delete/client"
-	"github.com/cedws/discord-delete/client/token"
+	"github.com/cedws/discord-delete/client/datapackage"
+	"github.com/cedws/discord-delete/client/ledger"
 )
 
 var (
-	verbose      bool
-	dryRun       bool
-	skipPinned   bool
-	minAge       uint
-	maxAge       uint
-	skipChannels []string
+	verbose          bool
+	dryRun           bool
+	skipPinned       bool
+	unarchiveThreads bool
+	searchThreads    bool
+	leaveGroupDMs    bool
+	minAge           uint
+	maxAge           uint
+	skipChannels     []string
+	onlyChannels     []string
+	mode             string
+	dataPackage      string
+	ledgerPath       string
+	verify           bool
+	keepLast         uint
+	keepNewerThan    string
+	reactions        bool
+	scrub            bool
+	scrubText        string
+	editOnly         bool
+	types            []string
+	interactive      bool
+	tokensFile       string
+	tokenEnvs        []string
+	parallel         bool
 )
 
 var rootCmd = &cobra.Command{
@@ -30,53 +55,228 @@ var rootCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		log.Warn("any tool that deletes your messages, including this one, could result in the termination of your account")
 
-		var tok string
-		var err error
+		if (scrub || editOnly) && scrubText == "" {
+			log.Fatal("scrub text must not be empty")
+		}
+		if interactive && parallel {
+			log.Fatal("interactive mode can't be used when running accounts in parallel")
+		}
+		if interactive && (mode == client.HistoryMode || reactions) {
+			log.Fatal("interactive mode previews with search, so it can't be used with --mode history or --reactions")
+		}
+		if verify && (mode == client.HistoryMode || reactions) {
+			log.Fatal("--verify relies on search, so it can't be used with --mode history or --reactions")
+		}
 
-		tok, def := os.LookupEnv("DISCORD_TOKEN")
-		if !def {
-			if tok, err = token.GetToken(); err != nil {
-				log.Debug(err)
-				log.Fatal("error retrieving token, pass DISCORD_TOKEN as an environment variable instead")
-			}
+		l := openLedger()
+		if l != nil {
+			defer l.Close()
 		}
 
-		client := client.New(tok)
-		client.SetDryRun(dryRun)
-		client.SetSkipChannels(skipChannels)
-		client.SetSkipPinned(skipPinned)
+		var confirm client.ConfirmFunc
+		if interactive {
+			confirm = promptScope(bufio.NewReader(os.Stdin))
+		}
 
-		if dryRun {
-			log.Infof("no messages will be deleted in dry-run mode")
+		if editOnly {
+			log.Infof("messages will be scrubbed but not deleted in edit-only mode")
 		}
 
-		if minAge > 0 {
-			if err := client.SetMinAge(minAge); err != nil {
-				log.Fatal(err)
+		configure := func(c *client.Client) {
+			applyPolicyFlags(c)
+			c.SetLeaveGroupDMs(leaveGroupDMs)
+			c.SetVerify(verify)
+			c.SetReactions(reactions)
+			if scrub || editOnly {
+				c.SetScrub(scrubText)
+			}
+			c.SetEditOnly(editOnly)
+			if confirm != nil {
+				c.SetConfirm(confirm)
+			}
+			if l != nil {
+				c.SetLedger(l)
 			}
-			log.Infof("deleting messages older than %v days", minAge)
 		}
 
-		if maxAge > 0 {
-			if err = client.SetMaxAge(maxAge); err != nil {
-				log.Fatal(err)
+		tokens := getTokens()
+		if len(tokens) > 1 {
+			if dataPackage != "" {
+				log.Fatal("a data package belongs to a single account, so --from-data-package can't be used with several tokens")
 			}
-			log.Infof("deleting messages newer than %v days", maxAge)
+			runAccounts(tokens, configure)
+			return
 		}
 
-		if err = client.Delete(); err != nil {
+		c := newClient(tokens[0])
+		validateToken(&c)
+		configure(&c)
+
+		defer stopOnInterrupt(&c)()
+		if err := c.Delete(); err != nil {
+			if errors.Is(err, client.ErrorQuit) {
+				log.Info("stopped deleting messages")
+				return
+			}
 			log.Fatal(err)
 		}
 	},
 }
 
+// newClient creates a client configured with the flags shared by every
+// command which works through the user's messages.
+func newClient(token string) client.Client {
+	c := client.New(token)
+	setDryRun(&c)
+	if err := c.SetSkipChannels(skipChannels); err != nil {
+		log.Fatal(err)
+	}
+	if err := c.SetOnlyChannels(onlyChannels); err != nil {
+		log.Fatal(err)
+	}
+	if err := c.SetMode(mode); err != nil {
+		log.Fatal(err)
+	}
+	c.SetSkipPinned(skipPinned)
+	c.SetUnarchiveThreads(unarchiveThreads)
+	c.SetSearchThreads(searchThreads)
+
+	if dataPackage != "" {
+		channels, err := datapackage.Open(dataPackage)
+		if err != nil {
+			log.Fatal(err)
+		}
+		c.SetDataPackage(channels)
+		log.Infof("found %v channels in data package", len(channels))
+	}
+
+	if minAge > 0 {
+		if err := c.SetMinAge(minAge); err != nil {
+			log.Fatal(err)
+		}
+		log.Infof("only including messages older than %v days", minAge)
+	}
+
+	if maxAge > 0 {
+		if err := c.SetMaxAge(maxAge); err != nil {
+			log.Fatal(err)
+		}
+		log.Infof("only including messages newer than %v days", maxAge)
+	}
+
+	return c
+}
+
+// setDryRun puts the client in dry-run mode if it was asked for.
+func setDryRun(c *client.Client) {
+	c.SetDryRun(dryRun)
+	if dryRun {
+		log.Infof("no messages will be changed in dry-run mode")
+	}
+}
+
+// openLedger opens the ledger given with --ledger for the caller to close, or
+// returns nil if there isn't one or nothing will be deleted.
+func openLedger() *ledger.Ledger {
+	if ledgerPath == "" || dryRun {
+		return nil
+	}
+
+	l, err := ledger.Open(ledgerPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Infof("recording deletions to ledger %v with run ID %v", ledgerPath, l.RunID())
+
+	return l
+}
+
+// stopOnInterrupt stops the client cleanly on the first interrupt, so that
+// threads it unarchived are archived again, and returns a function which stops
+// listening. Interrupting again quits straight away.
+func stopOnInterrupt(c *client.Client) func() {
+	stop := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-stop:
+			signal.Stop(stop)
+			log.Info("stopping, interrupt again to quit straight away")
+			c.Stop()
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(stop)
+		close(done)
+	}
+}
+
+// applyPolicyFlags configures the client with the flags which decide which of
+// the messages found are deleted.
+func applyPolicyFlags(c *client.Client) {
+	if keepNewerThan != "" {
+		d, err := client.ParseDuration(keepNewerThan)
+		if err != nil {
+			log.Fatal(err)
+		}
+		c.SetKeepNewerThan(d)
+	}
+	c.SetKeepLast(keepLast)
+	if err := c.SetTypes(types); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// addScopeFlags adds the flags which control where messages are looked for.
+func addScopeFlags(flags *pflag.FlagSet) {
+	flags.BoolVarP(&dryRun, "dry-run", "d", false, "perform dry run without changing anything")
+	flags.StringSliceVarP(&skipChannels, "skip", "s", []string{}, "skip specified channels/guilds, by ID, guild/#channel, guild/category or guild:, channel: and dm: name patterns")
+	flags.StringSliceVar(&onlyChannels, "only", []string{}, "only include specified channels/guilds, in the same forms as --skip")
+	flags.StringVar(&mode, "mode", client.SearchMode, "find messages with 'search', or walk the 'history' of DMs and channels selected with --only")
+	flags.BoolVarP(&skipPinned, "skip-pinned", "p", false, "skip pinned messages")
+	flags.BoolVar(&unarchiveThreads, "unarchive-threads", false, "temporarily unarchive threads to change messages inside them")
+	flags.BoolVar(&searchThreads, "threads", false, "search threads and forum posts individually after each guild")
+	flags.StringVar(&dataPackage, "from-data-package", "", "discover channels from a Discord data package (zip or directory)")
+}
+
+// addAgeFlags adds the flags which limit the age of messages looked for.
+func addAgeFlags(flags *pflag.FlagSet) {
+	flags.UintVarP(&minAge, "older-than-days", "o", 0, "minimum number in days of messages to be included")
+	flags.UintVarP(&maxAge, "newer-than-days", "n", 0, "maximum number in days of messages to be included")
+}
+
+// addPolicyFlags adds the flags which decide which of the messages found are
+// deleted.
+func addPolicyFlags(flags *pflag.FlagSet) {
+	flags.UintVar(&keepLast, "keep-last", 0, "keep this many of your most recent messages in each channel")
+	flags.StringVar(&keepNewerThan, "keep-newer-than", "", "keep messages sent within this duration (e.g. 12h, 7d) of your most recent message in each channel")
+	flags.StringSliceVar(&types, "types", []string{}, "opt message types in or out of deletion by name or number, e.g. --types=-channel_pinned_message,+21 (see the types command)")
+}
+
 func init() {
-	rootCmd.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "perform dry run without deleting anything")
-	rootCmd.Flags().UintVarP(&minAge, "older-than-days", "o", 0, "minimum number in days of messages to be deleted")
-	rootCmd.Flags().UintVarP(&maxAge, "newer-than-days", "n", 0, "maximum number in days of messages to be deleted")
-	rootCmd.Flags().StringSliceVarP(&skipChannels, "skip", "s", []string{}, "skip message deletion for specified channels/guilds")
-	rootCmd.Flags().BoolVarP(&skipPinned, "skip-pinned", "p", false, "skip message deletion for pinned messages")
-	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "enable verbose logging")
+	addScopeFlags(rootCmd.Flags())
+	addAgeFlags(rootCmd.Flags())
+	addPolicyFlags(rootCmd.Flags())
+	rootCmd.Flags().BoolVar(&reactions, "reactions", false, "also remove your reactions, walking the history of DMs and channels selected with --only")
+	rootCmd.Flags().BoolVar(&scrub, "scrub", false, "overwrite the content of messages before deleting them")
+	rootCmd.Flags().StringVar(&scrubText, "scrub-text", ".", "text to overwrite messages with when scrubbing")
+	rootCmd.Flags().BoolVar(&editOnly, "edit-only", false, "scrub messages without deleting them")
+	rootCmd.Flags().BoolVar(&leaveGroupDMs, "leave-group-dms", false, "leave group DMs once their messages have been deleted")
+	rootCmd.Flags().StringVar(&ledgerPath, "ledger", "", "append a record of every deleted message to this file")
+	rootCmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "preview each channel and guild and ask before deleting from it")
+	rootCmd.Flags().BoolVar(&verify, "verify", false, "search again after each channel or guild to confirm nothing remains")
+	rootCmd.Flags().StringVar(&tokensFile, "tokens-file", "", "delete messages from every account whose token is listed in this file, one per line")
+	rootCmd.Flags().StringSliceVar(&tokenEnvs, "token-env", []string{}, "delete messages from the account whose token is in this environment variable, may be repeated")
+	rootCmd.Flags().BoolVar(&parallel, "parallel", false, "delete messages from multiple accounts at the same time rather than one after another")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enable verbose logging")
+	rootCmd.PersistentFlags().BoolVar(&tokenStdin, "token-stdin", false, "read the token from the first line of stdin")
+	rootCmd.PersistentFlags().StringVar(&tokenFile, "token-file", "", "read the token from this file")
+	rootCmd.PersistentFlags().StringVar(&tokenCommand, "token-command", "", "read the token from the first line of this command's output, e.g. 'pass show discord'")
+	rootCmd.PersistentFlags().StringVar(&accountID, "account", "", "user ID of the account to use when retrieving the token from the discord client (see tokens list)")
 }
 
 func Execute() {
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/cedws/discord-delete/client/token"
+	"github.com/cedws/discord-delete/client/vault"
+)
+
+var (
+	tokenStdin   bool
+	tokenFile    string
+	tokenCommand string
+	accountID    string
+)
+
+// getToken finds the token to use, from the first of these which is given:
+//
+//  1. --token-stdin
+//  2. --token-file
+//  3. --token-command
+//  4. the DISCORD_TOKEN environment variable
+//  5. the token stored by the login command
+//  6. the Discord client, taking the account selected with --account if given
+//
+// --account only applies to the Discord client, so it's an error to give it
+// when the token comes from anywhere else.
+func getToken() string {
+	if tok, source, ok := explicitToken(); ok {
+		checkAccount(source)
+		log.Infof("using token from %v", source)
+		return tok
+	}
+
+	tok, def := os.LookupEnv("DISCORD_TOKEN")
+	if def {
+		checkAccount("DISCORD_TOKEN")
+		log.Info("using token from DISCORD_TOKEN")
+		return tok
+	}
+
+	tok, err := storedToken()
+	if err == nil {
+		checkAccount("the login command")
+		log.Info("using token stored by the login command")
+		return tok
+	}
+	if !errors.Is(err, vault.ErrorNotFound) {
+		log.Fatal(err)
+	}
+
+	tok, err = clientToken()
+	if errors.Is(err, token.ErrorNoAccount) {
+		log.Fatalf("%v, see the tokens list command for the accounts logged in to the discord client", err)
+	}
+	if err != nil {
+		log.Debug(err)
+		log.Fatal("error retrieving token, run the login command, use one of the --token flags or pass DISCORD_TOKEN as an environment variable instead")
+	}
+
+	return tok
+}
+
+// checkAccount fails if --account was given but the token came from source,
+// rather than letting it be ignored.
+func checkAccount(source string) {
+	if accountID != "" {
+		log.Fatalf("--account picks an account logged in to the discord client, but a token was already given by %v", source)
+	}
+}
+
+// clientToken retrieves the token from the Discord client, taking the account
+// selected with --account if given.
+func clientToken() (string, error) {
+	if accountID == "" {
+		tok, err := token.GetToken()
+		if err == nil {
+			log.Info("using token from the discord client")
+		}
+		return tok, err
+	}
+
+	tok, err := token.GetAccountToken(accountID)
+	if err == nil {
+		log.Infof("using token for account %v from the discord client", accountID)
+	}
+	return tok, err
+}
+
+// explicitToken returns the token from whichever of the --token flags was given,
+// along with where it came from.
+func explicitToken() (string, string, bool) {
+	var tok, source string
+
+	switch {
+	case tokenStdin:
+		source = "stdin"
+		line, err := readLine(os.Stdin)
+		if err != nil {
+			log.Fatalf("error reading token from stdin: %v", err)
+		}
+		tok = line
+	case tokenFile != "":
+		source = tokenFile
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			log.Fatalf("error reading token file: %v", err)
+		}
+		tok = string(data)
+	case tokenCommand != "":
+		source = "command " + tokenCommand
+		log.Debugf("running %v to get token", tokenCommand)
+		out, err := runTokenCommand(tokenCommand)
+		if err != nil {
+			log.Fatalf("error running token command: %v", err)
+		}
+		// Password managers such as pass keep the secret on the first line
+		tok, _, _ = strings.Cut(out, "\n")
+	default:
+		return "", "", false
+	}
+
+	tok = strings.TrimSpace(tok)
+	if tok == "" {
+		log.Fatal("token must not be empty")
+	}
+	return tok, source, true
+}
+
+// runTokenCommand runs the command through the shell and returns its output.
+// Anything it prints to stderr, such as a prompt for a passphrase, is passed
+// through to the user.
+func runTokenCommand(command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	return stdout.String(), nil
+}
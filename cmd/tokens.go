@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/cedws/discord-delete/client/token"
+)
+
+var tokensCmd = &cobra.Command{
+	Use:   "tokens",
+	Short: "Inspect the tokens stored by the Discord client",
+}
+
+var tokensListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the accounts logged in to each build of the Discord client",
+	Long: `List the accounts logged in to each build of the Discord client. Pass one of
+the user IDs to --account to use its token rather than the first one found.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		accounts, err := token.Accounts()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "BUILD\tUSER ID")
+		for _, account := range accounts {
+			fmt.Fprintf(w, "%v\t%v\n", account.Build, account.UserID)
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	tokensCmd.AddCommand(tokensListCmd)
+	rootCmd.AddCommand(tokensCmd)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/cedws/discord-delete/tui"
+)
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Browse and select what to delete in a full-screen interface",
+	Run: func(cmd *cobra.Command, args []string) {
+		c := newClient(getToken())
+		validateToken(&c)
+
+		settings := tui.Settings{
+			OlderThanDays: minAge,
+			NewerThanDays: maxAge,
+			SkipPinned:    skipPinned,
+			DryRun:        dryRun,
+		}
+		if err := tui.Run(&c, settings); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	addScopeFlags(tuiCmd.Flags())
+	addAgeFlags(tuiCmd.Flags())
+	rootCmd.AddCommand(tuiCmd)
+}
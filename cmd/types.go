@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cedws/discord-delete/client"
+)
+
+var typesCmd = &cobra.Command{
+	Use:   "types",
+	Short: "List message types and whether they are deleted by default",
+	Run: func(cmd *cobra.Command, args []string) {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TYPE\tNAME\tDELETED BY DEFAULT")
+		for _, t := range client.MessageTypes {
+			fmt.Fprintf(w, "%v\t%v\t%v\n", t.Type, t.Name, t.Deletable)
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(typesCmd)
+}
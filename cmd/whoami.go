@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/cedws/discord-delete/client"
+	"github.com/cedws/discord-delete/client/snowflake"
+)
+
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Check the token and show the account it belongs to",
+	Run: func(cmd *cobra.Command, args []string) {
+		c := client.New(getToken())
+		me := validateToken(&c)
+
+		guilds, err := c.Guilds()
+		if err != nil {
+			log.Fatal(err)
+		}
+		channels, err := c.Channels()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		dms, groups := 0, 0
+		for _, channel := range channels {
+			switch channel.Type {
+			case client.DirectChannel:
+				dms++
+			case client.GroupDirectChannel:
+				groups++
+			}
+		}
+
+		created := "unknown"
+		if id, err := strconv.ParseInt(me.ID, 10, 64); err == nil {
+			created = time.UnixMilli(snowflake.FromSnowflake(id)).Local().Format(time.RFC1123)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "USERNAME\t%v\n", me.Username)
+		fmt.Fprintf(w, "ID\t%v\n", me.ID)
+		fmt.Fprintf(w, "CREATED\t%v\n", created)
+		fmt.Fprintf(w, "MFA\t%v\n", enabled(me.MFAEnabled))
+		fmt.Fprintf(w, "EMAIL VERIFIED\t%v\n", yesNo(me.Verified))
+		fmt.Fprintf(w, "GUILDS\t%v\n", len(guilds))
+		fmt.Fprintf(w, "DMS\t%v\n", dms)
+		fmt.Fprintf(w, "GROUP DMS\t%v\n", groups)
+		w.Flush()
+	},
+}
+
+// validateToken checks the token before any work starts, so that a bad token
+// fails straight away with an explanation rather than partway through a run.
+func validateToken(c *client.Client) client.Me {
+	me, err := c.Validate()
+	if errors.Is(err, client.ErrorInvalidToken) {
+		log.Fatal("discord rejected the token, which happens after logging out or changing your password. " +
+			"Log back in to discord and run the login command again, or pass a fresh token in DISCORD_TOKEN")
+	}
+	if err != nil {
+		log.Fatalf("error checking token: %v", err)
+	}
+
+	log.Debugf("token belongs to %v (%v)", me.Username, me.ID)
+	return me
+}
+
+func enabled(b bool) string {
+	if b {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func init() {
+	rootCmd.AddCommand(whoamiCmd)
+}
@@ -0,0 +1,87 @@
+package tui
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// Names of keys which don't produce a character
+const (
+	keyUp        = "up"
+	keyDown      = "down"
+	keyEnter     = "enter"
+	keyEscape    = "esc"
+	keySpace     = "space"
+	keyBackspace = "backspace"
+	keyInterrupt = "ctrl+c"
+)
+
+// readKeys decodes key presses from a terminal in raw mode. The channel is
+// closed when reading fails.
+func readKeys(r io.Reader) <-chan string {
+	keys := make(chan string)
+
+	go func() {
+		defer close(keys)
+
+		buf := make([]byte, 16)
+		for {
+			n, err := r.Read(buf)
+			if err != nil {
+				return
+			}
+			if key := decodeKey(buf[:n]); key != "" {
+				keys <- key
+			}
+		}
+	}()
+
+	return keys
+}
+
+func decodeKey(b []byte) string {
+	switch {
+	case len(b) == 0:
+		return ""
+	case len(b) >= 3 && b[0] == 0x1b && b[1] == '[':
+		switch b[2] {
+		case 'A':
+			return keyUp
+		case 'B':
+			return keyDown
+		}
+		return ""
+	case b[0] == 0x1b:
+		return keyEscape
+	case b[0] == 0x03:
+		return keyInterrupt
+	case b[0] == '\r' || b[0] == '\n':
+		return keyEnter
+	case b[0] == ' ':
+		return keySpace
+	case b[0] == 0x7f || b[0] == 0x08:
+		return keyBackspace
+	}
+
+	r, _ := utf8.DecodeRune(b)
+	if r == utf8.RuneError {
+		return ""
+	}
+	return string(r)
+}
+
+// prompt reads a number of days for one of the settings.
+type prompt struct {
+	label string
+	value string
+	apply func(settings *Settings, days uint)
+}
+
+func (p *prompt) edit(key string) {
+	switch {
+	case key == keyBackspace && p.value != "":
+		p.value = p.value[:len(p.value)-1]
+	case len(key) == 1 && key[0] >= '0' && key[0] <= '9':
+		p.value += key
+	}
+}
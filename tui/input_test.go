@@ -0,0 +1,24 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeKey(t *testing.T) {
+	assert.Equal(t, keyUp, decodeKey([]byte("\x1b[A")))
+	assert.Equal(t, keyDown, decodeKey([]byte("\x1b[B")))
+	assert.Equal(t, keyEscape, decodeKey([]byte("\x1b")))
+	assert.Equal(t, keyEnter, decodeKey([]byte("\r")))
+	assert.Equal(t, keySpace, decodeKey([]byte(" ")))
+	assert.Equal(t, "q", decodeKey([]byte("q")))
+}
+
+func TestPromptEdit(t *testing.T) {
+	p := prompt{}
+	for _, key := range []string{"3", "x", "0", keyBackspace, "5"} {
+		p.edit(key)
+	}
+	assert.Equal(t, "35", p.value)
+}
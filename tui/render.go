@@ -0,0 +1,188 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// Escape sequences for controlling the terminal
+const (
+	enterAltScreen = "\x1b[?1049h"
+	exitAltScreen  = "\x1b[?1049l"
+	hideCursor     = "\x1b[?25l"
+	showCursor     = "\x1b[?25h"
+	clearScreen    = "\x1b[H\x1b[2J"
+	reverseVideo   = "\x1b[7m"
+	resetStyle     = "\x1b[0m"
+)
+
+const helpLine = "↑/↓ move  space select  a all  v preview  o older than  n newer than  p pinned  d dry run  r run  q quit"
+
+// render draws the whole screen. Lines end in \r\n because the terminal is in
+// raw mode.
+func (a *app) render() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		width, height = 80, 24
+	}
+
+	var lines []string
+
+	name := a.me.Username
+	if name == "" {
+		name = "..."
+	}
+	lines = append(lines, fmt.Sprintf("discord-delete  %v", name))
+	lines = append(lines, a.settingsLine())
+	lines = append(lines, strings.Repeat("─", width))
+
+	// Whatever is left after the header, log pane and footer is for the list
+	listHeight := height - len(lines) - logLines - 3
+	if listHeight < 1 {
+		listHeight = 1
+	}
+
+	if a.preview != nil {
+		lines = append(lines, a.previewLines(listHeight)...)
+	} else {
+		lines = append(lines, a.listLines(listHeight)...)
+	}
+
+	lines = append(lines, strings.Repeat("─", width))
+	for i := 0; i < logLines; i++ {
+		if i < len(a.logs) {
+			lines = append(lines, a.logs[i])
+		} else {
+			lines = append(lines, "")
+		}
+	}
+	lines = append(lines, strings.Repeat("─", width))
+	lines = append(lines, a.footerLine())
+
+	var b strings.Builder
+	b.WriteString(clearScreen)
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(truncate(line, width))
+	}
+	fmt.Fprint(a.out, b.String())
+}
+
+func (a *app) settingsLine() string {
+	older, newer := "any", "any"
+	if a.settings.OlderThanDays > 0 {
+		older = fmt.Sprintf("%vd", a.settings.OlderThanDays)
+	}
+	if a.settings.NewerThanDays > 0 {
+		newer = fmt.Sprintf("%vd", a.settings.NewerThanDays)
+	}
+
+	line := fmt.Sprintf("older than: %v  newer than: %v  skip pinned: %v", older, newer, yesNo(a.settings.SkipPinned))
+	if a.settings.DryRun {
+		line += "  [dry run]"
+	}
+	return line
+}
+
+func (a *app) listLines(height int) []string {
+	// Keep the cursor on screen
+	if a.cursor < a.scroll {
+		a.scroll = a.cursor
+	}
+	if a.cursor >= a.scroll+height {
+		a.scroll = a.cursor - height + 1
+	}
+
+	var lines []string
+	for i := a.scroll; i < len(a.items) && i < a.scroll+height; i++ {
+		it := a.items[i]
+
+		check := "[ ]"
+		if it.selected {
+			check = "[x]"
+		}
+
+		count := "?"
+		if it.count >= 0 {
+			count = fmt.Sprint(it.count)
+		}
+
+		progress := ""
+		if it.status != "" {
+			progress = fmt.Sprintf("%v, %v deleted", it.status, it.deleted)
+		}
+
+		line := fmt.Sprintf("%v %-6v %-32v %8v  %v", check, it.kind, truncate(it.label, 32), count, progress)
+		if i == a.cursor {
+			line = reverseVideo + line + resetStyle
+		}
+		lines = append(lines, line)
+	}
+
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+func (a *app) previewLines(height int) []string {
+	lines := []string{
+		fmt.Sprintf("%v '%v': %v messages, most recent:", a.preview.Kind, a.preview.Name, a.preview.TotalResults),
+	}
+
+	for _, msg := range a.preview.Samples {
+		if len(lines) == height {
+			break
+		}
+
+		content := strings.Join(strings.Fields(msg.Content), " ")
+		if content == "" {
+			content = "(no text)"
+		}
+		lines = append(lines, "  "+content)
+	}
+
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+func (a *app) footerLine() string {
+	switch {
+	case a.input != nil:
+		return fmt.Sprintf("%v: %v_", a.input.label, a.input.value)
+	case a.confirm:
+		return "delete messages from the selected DMs and guilds? [y/N]"
+	case a.preview != nil:
+		return "press any key to go back"
+	case a.status != "":
+		return a.status
+	}
+	return helpLine
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// truncate shortens a line to fit the width of the terminal. Lines containing
+// escape sequences are left alone since they're only used for the cursor.
+func truncate(line string, width int) string {
+	if strings.Contains(line, "\x1b") || utf8.RuneCountInString(line) <= width {
+		return line
+	}
+	return string([]rune(line)[:width])
+}
@@ -0,0 +1,579 @@
+// Package tui implements a full-screen terminal interface for choosing which
+// DMs and guilds to delete messages from and monitoring the deletion.
+package tui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/term"
+
+	"github.com/cedws/discord-delete/client"
+)
+
+// Number of log lines kept for the log pane
+const logLines = 6
+
+// Kinds of scope that can be selected
+const (
+	kindDM     = "DM"
+	kindGroup  = "Group"
+	kindFriend = "Friend"
+	kindGuild  = "Guild"
+)
+
+type item struct {
+	kind     string
+	label    string
+	channel  client.Channel
+	relation *client.Relationship
+	// count is the number of matched messages, or -1 if not known yet
+	count    int
+	deleted  int
+	selected bool
+	status   string
+}
+
+// Settings are the filters which can be changed from within the interface.
+type Settings struct {
+	OlderThanDays uint
+	NewerThanDays uint
+	SkipPinned    bool
+	DryRun        bool
+}
+
+type app struct {
+	mu sync.Mutex
+
+	client   *client.Client
+	me       client.Me
+	settings Settings
+
+	items   []*item
+	cursor  int
+	scroll  int
+	preview *client.ScopePreview
+	input   *prompt
+	confirm bool
+	running bool
+	status  string
+	logs    []string
+
+	// generation is bumped whenever the settings change so that stale counts
+	// are abandoned
+	generation int
+
+	// jobs run on the worker one after another, while priority jobs can also
+	// run between the steps of a long job such as counting
+	jobs     chan func()
+	priority chan func()
+	redraw   chan struct{}
+	out      io.Writer
+}
+
+// Run takes over the terminal until the user quits. The client is only used
+// from a single worker goroutine, so it doesn't need to be safe for concurrent
+// use.
+func Run(c *client.Client, settings Settings) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return fmt.Errorf("tui: standard input is not a terminal")
+	}
+
+	a := &app{
+		client:   c,
+		settings: settings,
+		status:   "loading...",
+		jobs:     make(chan func(), 16),
+		priority: make(chan func(), 16),
+		redraw:   make(chan struct{}, 1),
+		out:      os.Stdout,
+	}
+
+	// Logs would draw over the interface, so show them in a pane instead
+	defer redirectLogs(a)()
+
+	c.SetOnDelete(a.onDelete)
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("tui: error entering raw mode: %w", err)
+	}
+	defer term.Restore(fd, state)
+
+	fmt.Fprint(a.out, enterAltScreen+hideCursor)
+	defer fmt.Fprint(a.out, showCursor+exitAltScreen)
+
+	go a.work()
+	a.jobs <- a.load
+
+	keys := readKeys(os.Stdin)
+	a.render()
+
+	for {
+		select {
+		case key, ok := <-keys:
+			if !ok || !a.handleKey(key) {
+				return nil
+			}
+		case <-a.redraw:
+		}
+		a.render()
+	}
+}
+
+// redirectLogs sends logs to the hook instead of the terminal and returns a
+// function which puts things back as they were.
+func redirectLogs(hook log.Hook) func() {
+	logger := log.StandardLogger()
+	out := logger.Out
+
+	hooks := make(log.LevelHooks)
+	for level, levelHooks := range logger.Hooks {
+		hooks[level] = append([]log.Hook(nil), levelHooks...)
+	}
+
+	logger.SetOutput(io.Discard)
+	logger.AddHook(hook)
+
+	return func() {
+		logger.ReplaceHooks(hooks)
+		logger.SetOutput(out)
+	}
+}
+
+func (a *app) work() {
+	for {
+		select {
+		case job := <-a.priority:
+			job()
+		case job := <-a.jobs:
+			job()
+		}
+		a.requestRedraw()
+	}
+}
+
+// runPriority runs any waiting priority jobs. It must only be called from the
+// worker.
+func (a *app) runPriority() {
+	for {
+		select {
+		case job := <-a.priority:
+			job()
+			a.requestRedraw()
+		default:
+			return
+		}
+	}
+}
+
+// enqueue queues a job for the worker without blocking, because the lock is
+// held. It must be called with the lock held.
+func (a *app) enqueue(jobs chan func(), job func()) {
+	select {
+	case jobs <- job:
+	default:
+		a.status = "busy, try again in a moment"
+	}
+}
+
+// changeSettings counts messages again with new settings, abandoning any
+// count in progress. It must be called with the lock held.
+func (a *app) changeSettings(settings Settings) {
+	a.generation++
+	generation := a.generation
+	a.enqueue(a.jobs, func() { a.applySettings(settings, generation) })
+}
+
+func (a *app) requestRedraw() {
+	select {
+	case a.redraw <- struct{}{}:
+	default:
+	}
+}
+
+func (a *app) setStatus(format string, args ...any) {
+	a.mu.Lock()
+	a.status = fmt.Sprintf(format, args...)
+	a.mu.Unlock()
+	a.requestRedraw()
+}
+
+// load fetches the user's DMs, friends and guilds, then counts their messages.
+func (a *app) load() {
+	me, err := a.client.Me()
+	if err != nil {
+		a.setStatus("error fetching profile information: %v", err)
+		return
+	}
+
+	channels, err := a.client.Channels()
+	if err != nil {
+		a.setStatus("error fetching channels: %v", err)
+		return
+	}
+	relationships, err := a.client.Relationships()
+	if err != nil {
+		a.setStatus("error fetching relationships: %v", err)
+		return
+	}
+	guilds, err := a.client.Guilds()
+	if err != nil {
+		a.setStatus("error fetching guilds: %v", err)
+		return
+	}
+
+	var items []*item
+	dms := make(map[string]bool)
+
+	for _, channel := range channels {
+		kind := kindDM
+		if channel.Type == client.GroupDirectChannel {
+			kind = kindGroup
+		} else if len(channel.Recipients) == 1 {
+			dms[channel.Recipients[0].ID] = true
+		}
+		items = append(items, &item{kind: kind, label: channel.DisplayName(), channel: channel, count: -1})
+	}
+	for i, relation := range relationships {
+		if dms[relation.ID] {
+			continue
+		}
+		items = append(items, &item{kind: kindFriend, label: relation.Recipient.Username, relation: &relationships[i], count: -1})
+	}
+	for _, guild := range guilds {
+		items = append(items, &item{kind: kindGuild, label: guild.Name, channel: guild, count: -1})
+	}
+
+	a.mu.Lock()
+	a.me = me
+	a.items = items
+	a.status = ""
+	settings, generation := a.settings, a.generation
+	a.mu.Unlock()
+
+	a.applySettings(settings, generation)
+}
+
+// applySettings configures the client and counts messages again, unless the
+// settings have been changed again since. It must only be called from the
+// worker.
+func (a *app) applySettings(settings Settings, generation int) {
+	a.mu.Lock()
+	if generation != a.generation {
+		a.mu.Unlock()
+		return
+	}
+	a.mu.Unlock()
+
+	a.client.SetMinAge(settings.OlderThanDays)
+	a.client.SetMaxAge(settings.NewerThanDays)
+	a.client.SetSkipPinned(settings.SkipPinned)
+	a.client.SetDryRun(settings.DryRun)
+
+	a.mu.Lock()
+	a.settings = settings
+	items := a.items
+	for _, it := range items {
+		it.count = -1
+	}
+	a.mu.Unlock()
+
+	a.count(generation, items)
+}
+
+func (a *app) count(generation int, items []*item) {
+	for i, it := range items {
+		a.runPriority()
+
+		a.mu.Lock()
+		stale := a.generation != generation
+		a.mu.Unlock()
+		if stale {
+			return
+		}
+
+		a.setStatus("counting messages (%v/%v)", i+1, len(items))
+
+		if !opened(it) {
+			// Counting isn't worth opening a DM for
+			continue
+		}
+
+		preview, err := a.previewItem(it)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+
+		a.mu.Lock()
+		it.count = preview.TotalResults
+		a.mu.Unlock()
+		a.requestRedraw()
+	}
+
+	a.setStatus("")
+}
+
+// opened returns whether the item has a channel, which is only missing for
+// friends without an open DM.
+func opened(it *item) bool {
+	return it.channel.ID != ""
+}
+
+// openItem opens a DM with a friend if there isn't one open already. It must
+// only be called from the worker.
+func (a *app) openItem(it *item) error {
+	if opened(it) {
+		return nil
+	}
+
+	channel, err := a.client.RelationshipChannel(it.relation.Recipient)
+	if err != nil {
+		return fmt.Errorf("error resolving relationship to channel: %w", err)
+	}
+
+	a.mu.Lock()
+	it.channel = channel
+	a.mu.Unlock()
+	return nil
+}
+
+// previewItem searches the item's messages. It must only be called from the
+// worker.
+func (a *app) previewItem(it *item) (client.ScopePreview, error) {
+	if it.kind == kindGuild {
+		return a.client.PreviewGuild(a.me, it.channel)
+	}
+	return a.client.PreviewChannel(a.me, it.channel)
+}
+
+// deleteSelected deletes from every selected item in turn. It must only be
+// called from the worker.
+func (a *app) deleteSelected() {
+	a.mu.Lock()
+	var selected []*item
+	for _, it := range a.items {
+		if it.selected {
+			selected = append(selected, it)
+		}
+	}
+	a.running = true
+	a.mu.Unlock()
+
+	for i, it := range selected {
+		a.mu.Lock()
+		it.status = "deleting"
+		a.status = fmt.Sprintf("deleting from %v (%v/%v)", it.label, i+1, len(selected))
+		a.mu.Unlock()
+		a.requestRedraw()
+
+		if err := a.openItem(it); err != nil {
+			a.mu.Lock()
+			it.status = "failed"
+			a.mu.Unlock()
+
+			log.Error(err)
+			continue
+		}
+
+		var err error
+		if it.kind == kindGuild {
+			err = a.client.DeleteFromGuild(a.me, it.channel)
+		} else {
+			err = a.client.DeleteFromChannel(a.me, it.channel)
+		}
+
+		a.mu.Lock()
+		it.status = "done"
+		if err != nil {
+			it.status = "failed"
+		}
+		a.mu.Unlock()
+
+		if err != nil {
+			log.Error(err)
+		}
+	}
+
+	a.mu.Lock()
+	a.running = false
+	a.status = fmt.Sprintf("finished: %v messages deleted", a.client.DeletedCount())
+	a.mu.Unlock()
+}
+
+// onDelete is called by the client from the worker after each deletion.
+func (a *app) onDelete(msg client.Message) {
+	a.mu.Lock()
+	for _, it := range a.items {
+		if it.status == "deleting" {
+			it.deleted++
+		}
+	}
+	a.mu.Unlock()
+	a.requestRedraw()
+}
+
+// Levels implements log.Hook.
+func (a *app) Levels() []log.Level {
+	return []log.Level{log.ErrorLevel, log.WarnLevel, log.InfoLevel}
+}
+
+// Fire implements log.Hook, keeping the most recent lines for the log pane.
+func (a *app) Fire(entry *log.Entry) error {
+	a.mu.Lock()
+	a.logs = append(a.logs, entry.Message)
+	if len(a.logs) > logLines {
+		a.logs = a.logs[len(a.logs)-logLines:]
+	}
+	a.mu.Unlock()
+	a.requestRedraw()
+	return nil
+}
+
+// handleKey updates the state in response to a key press and returns false
+// if the user wants to quit.
+func (a *app) handleKey(key string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.input != nil {
+		input := a.input
+		switch key {
+		case keyEnter:
+			a.input = nil
+			a.applyPrompt(input)
+		case keyEscape:
+			a.input = nil
+		default:
+			input.edit(key)
+		}
+		return true
+	}
+
+	if a.preview != nil {
+		a.preview = nil
+		return true
+	}
+
+	if a.confirm {
+		a.confirm = false
+		if key == "y" {
+			// Counting is pointless once deletion starts
+			a.generation++
+			a.running = true
+			a.enqueue(a.jobs, a.deleteSelected)
+		}
+		return true
+	}
+
+	switch key {
+	case "q", keyInterrupt:
+		return false
+	case keyUp, "k":
+		if a.cursor > 0 {
+			a.cursor--
+		}
+	case keyDown, "j":
+		if a.cursor < len(a.items)-1 {
+			a.cursor++
+		}
+	}
+
+	if a.running {
+		// Selection and settings are fixed while deleting
+		return true
+	}
+
+	switch key {
+	case keySpace:
+		if a.cursor < len(a.items) {
+			a.items[a.cursor].selected = !a.items[a.cursor].selected
+		}
+	case "a":
+		all := true
+		for _, it := range a.items {
+			all = all && it.selected
+		}
+		for _, it := range a.items {
+			it.selected = !all
+		}
+	case "o":
+		a.input = &prompt{
+			label: "delete messages older than (days, 0 for any)",
+			apply: func(s *Settings, days uint) { s.OlderThanDays = days },
+		}
+	case "n":
+		a.input = &prompt{
+			label: "delete messages newer than (days, 0 for any)",
+			apply: func(s *Settings, days uint) { s.NewerThanDays = days },
+		}
+	case "p":
+		settings := a.settings
+		settings.SkipPinned = !settings.SkipPinned
+		a.settings = settings
+		a.changeSettings(settings)
+	case "d":
+		settings := a.settings
+		settings.DryRun = !settings.DryRun
+		a.settings = settings
+		a.changeSettings(settings)
+	case "v", keyEnter:
+		if a.cursor < len(a.items) {
+			it := a.items[a.cursor]
+			a.status = fmt.Sprintf("loading preview of %v...", it.label)
+			a.enqueue(a.priority, func() { a.showPreview(it) })
+		}
+	case "r":
+		for _, it := range a.items {
+			if it.selected {
+				a.confirm = true
+				break
+			}
+		}
+		if !a.confirm {
+			a.status = "select at least one DM or guild with space first"
+		}
+	}
+
+	return true
+}
+
+// applyPrompt applies a number of days entered at a prompt. The lock must be
+// held.
+func (a *app) applyPrompt(input *prompt) {
+	days, err := strconv.ParseUint(input.value, 10, 32)
+	if err != nil {
+		a.status = fmt.Sprintf("invalid number of days '%v'", input.value)
+		return
+	}
+
+	settings := a.settings
+	input.apply(&settings, uint(days))
+	a.settings = settings
+	a.changeSettings(settings)
+}
+
+func (a *app) showPreview(it *item) {
+	if !opened(it) {
+		a.setStatus("no DM is open with %v yet, one will be opened when deleting", it.label)
+		return
+	}
+
+	preview, err := a.previewItem(it)
+	if err != nil {
+		a.setStatus("%v", err)
+		return
+	}
+
+	a.mu.Lock()
+	it.count = preview.TotalResults
+	a.preview = &preview
+	a.status = ""
+	a.mu.Unlock()
+}
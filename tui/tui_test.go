@@ -0,0 +1,76 @@
+package tui
+
+import (
+	"bytes"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cedws/discord-delete/client"
+)
+
+func newTestApp(items ...*item) *app {
+	return &app{
+		items:    items,
+		jobs:     make(chan func(), 16),
+		priority: make(chan func(), 16),
+		redraw:   make(chan struct{}, 1),
+	}
+}
+
+func TestRedirectLogs(t *testing.T) {
+	defer log.SetOutput(log.StandardLogger().Out)
+	var out bytes.Buffer
+	log.SetOutput(&out)
+
+	a := newTestApp()
+	restore := redirectLogs(a)
+	log.Info("in the pane")
+	restore()
+	log.Info("on the terminal")
+
+	assert.Equal(t, []string{"in the pane"}, a.logs)
+	assert.NotContains(t, out.String(), "in the pane")
+	assert.Contains(t, out.String(), "on the terminal")
+	assert.Empty(t, log.StandardLogger().Hooks)
+}
+
+func TestCountSkipsUnopenedFriends(t *testing.T) {
+	// Counting a friend without an open DM would need the client
+	friend := &item{kind: kindFriend, relation: &client.Relationship{ID: "1"}, count: -1}
+	a := newTestApp(friend)
+
+	a.count(a.generation, a.items)
+	assert.Equal(t, -1, friend.count)
+	assert.False(t, opened(friend))
+
+	a.showPreview(friend)
+	assert.Nil(t, a.preview)
+	assert.Contains(t, a.status, "no DM is open")
+}
+
+func TestHandleKey(t *testing.T) {
+	a := newTestApp(&item{label: "a"}, &item{label: "b"})
+
+	assert.True(t, a.handleKey("r"))
+	assert.False(t, a.confirm)
+
+	a.handleKey(keyDown)
+	a.handleKey(keySpace)
+	assert.False(t, a.items[0].selected)
+	assert.True(t, a.items[1].selected)
+
+	a.handleKey("r")
+	assert.True(t, a.confirm)
+	a.handleKey("n")
+	assert.False(t, a.confirm)
+	assert.False(t, a.running)
+
+	a.handleKey("a")
+	assert.True(t, a.items[0].selected && a.items[1].selected)
+	a.handleKey("a")
+	assert.False(t, a.items[0].selected || a.items[1].selected)
+
+	assert.False(t, a.handleKey("q"))
+}